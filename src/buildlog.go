@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogsKeep is how many build logs are kept in .marn/logs
+const defaultLogsKeep = 50
+
+// BuildLog is the full output of one Maven invocation written to .marn/logs
+type BuildLog struct {
+	Path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newBuildLog creates the log file for a Maven invocation in a project directory
+// Logs of all projects, including local dependencies, go to the current project
+// The header names the Maven executable that runs the build, like ./mvnw or mvnd
+func newBuildLog(projectDir string, maven Maven, args []string) (*BuildLog, error) {
+	dir := filepath.Join(currentDir, ".marn", "logs")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	project := getPomArtifactID(filepath.Join(projectDir, "pom.xml"))
+	if project == "" {
+		project = filepath.Base(projectDir)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.log", time.Now().Format("20060102-150405.000"), sanitizeLogName(project), logCommandName(args))
+
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(file, "$ %s %s\n# %s\n\n", maven.Name, strings.Join(args, " "), projectDir)
+	rotateBuildLogs(dir)
+
+	return &BuildLog{Path: file.Name(), file: file}, nil
+}
+
+// Write appends output to the log without its color codes
+func (l *BuildLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.WriteString(stripColors(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// WriteLine appends a single line to the log
+func (l *BuildLog) WriteLine(line string) {
+	l.Write([]byte(line + "\n"))
+}
+
+// Close closes the log file
+func (l *BuildLog) Close() {
+	l.file.Close()
+}
+
+// RelPath returns the log path relative to the project
+func (l *BuildLog) RelPath() string {
+	if rel, err := filepath.Rel(currentDir, l.Path); err == nil {
+		return rel
+	}
+
+	return l.Path
+}
+
+// logCommandName builds the command part of a log file name from the Maven goals
+func logCommandName(args []string) string {
+	var goals []string
+
+	for _, arg := range args {
+
+		if !strings.HasPrefix(arg, "-") {
+			goals = append(goals, arg)
+		}
+	}
+
+	if len(goals) == 0 {
+		return "mvn"
+	}
+
+	return sanitizeLogName(strings.Join(goals, "-"))
+}
+
+// sanitizeLogName replaces characters that are not safe in file names
+func sanitizeLogName(name string) string {
+	return regexp.MustCompile(`[^A-Za-z0-9_.-]+`).ReplaceAllString(name, "-")
+}
+
+// getLogsKeep returns how many logs to keep, from marn.logs.keep
+func getLogsKeep() int {
+	if keep, err := strconv.Atoi(getProperty("marn.logs.keep")); err == nil && keep > 0 {
+		return keep
+	}
+
+	return defaultLogsKeep
+}
+
+// rotateBuildLogs removes the oldest logs beyond the number to keep
+func rotateBuildLogs(dir string) {
+	logs := listBuildLogs(dir)
+
+	for len(logs) > getLogsKeep() {
+		os.Remove(logs[0])
+		logs = logs[1:]
+	}
+}
+
+// listBuildLogs returns the log files oldest first
+// Names start with a timestamp, so they sort by age
+func listBuildLogs(dir string) []string {
+	logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	sort.Strings(logs)
+	return logs
+}
+
+// showLogs implements 'marn logs', listing the logs or printing one
+func showLogs() {
+	dir := filepath.Join(currentDir, ".marn", "logs")
+	logs := listBuildLogs(dir)
+
+	if len(logs) == 0 {
+		fmt.Printf("%sNo build logs yet%s\n", colors.Yellow, colors.Reset)
+		return
+	}
+
+	if len(os.Args) > 2 {
+		arg := os.Args[2]
+		index := 1
+
+		if arg != "--last" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > len(logs) {
+				fmt.Printf("%sError: Unknown log '%s', run 'marn logs' to list them%s\n", colors.Red, arg, colors.Reset)
+				os.Exit(1)
+			}
+
+			index = n
+		}
+
+		printLogFile(logs[len(logs)-index])
+		return
+	}
+
+	fmt.Printf("%sBuild logs (newest first):%s\n", colors.Blue, colors.Reset)
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		info, err := os.Stat(logs[i])
+		if err != nil {
+			continue
+		}
+
+		fmt.Printf("  %3d  %s  %7s  %s\n", len(logs)-i, info.ModTime().Format("2006-01-02 15:04:05"), formatSize(info.Size()), filepath.Base(logs[i]))
+	}
+
+	fmt.Println()
+	fmt.Println("Run 'marn logs --last' or 'marn logs <n>' to show a log.")
+}
+
+// printLastBuildLog prints the most recent build log
+func printLastBuildLog() {
+	logs := listBuildLogs(filepath.Join(currentDir, ".marn", "logs"))
+
+	if len(logs) == 0 {
+		fmt.Printf("%sNo build logs yet%s\n", colors.Yellow, colors.Reset)
+		return
+	}
+
+	printLogFile(logs[len(logs)-1])
+}
+
+// printLogFile prints a log file with its name
+func printLogFile(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("%sError: Could not read %s: %v%s\n", colors.Red, path, err, colors.Reset)
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s%s\n", colors.Blue, filepath.Base(path), colors.Reset)
+	fmt.Print(string(content))
+	fmt.Println()
+}
+
+// formatSize formats a file size for listings
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1fM", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1fK", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ClassInfo holds the parts of a .class file that define its shape
+type ClassInfo struct {
+	Name       string
+	SuperClass string
+	Interfaces []string
+	Fields     []string // name:descriptor
+	Methods    []string // name:descriptor
+	References []string // classes used by this class
+}
+
+// Constant pool tags from the JVM specification
+const (
+	cpUtf8               = 1
+	cpInteger            = 3
+	cpFloat              = 4
+	cpLong               = 5
+	cpDouble             = 6
+	cpClass              = 7
+	cpString             = 8
+	cpFieldref           = 9
+	cpMethodref          = 10
+	cpInterfaceMethodref = 11
+	cpNameAndType        = 12
+	cpMethodHandle       = 15
+	cpMethodType         = 16
+	cpDynamic            = 17
+	cpInvokeDynamic      = 18
+	cpModule             = 19
+	cpPackage            = 20
+)
+
+// descriptorClassRe finds the class names in a field or method descriptor
+var descriptorClassRe = regexp.MustCompile(`L([^;]+);`)
+
+// classReader reads big-endian values from a class file
+type classReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// u1 reads an unsigned byte
+func (r *classReader) u1() int {
+	if r.err != nil || r.pos+1 > len(r.data) {
+		r.err = fmt.Errorf("truncated class file")
+		return 0
+	}
+
+	v := r.data[r.pos]
+	r.pos++
+	return int(v)
+}
+
+// u2 reads an unsigned 16-bit value
+func (r *classReader) u2() int {
+	if r.err != nil || r.pos+2 > len(r.data) {
+		r.err = fmt.Errorf("truncated class file")
+		return 0
+	}
+
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return int(v)
+}
+
+// u4 reads an unsigned 32-bit value
+func (r *classReader) u4() int {
+	if r.err != nil || r.pos+4 > len(r.data) {
+		r.err = fmt.Errorf("truncated class file")
+		return 0
+	}
+
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return int(v)
+}
+
+// bytes reads n raw bytes
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("truncated class file")
+		return nil
+	}
+
+	v := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+// constantPool holds the UTF-8 strings and class references of a class file
+type constantPool struct {
+	utf8    map[int]string
+	classes map[int]int // class index -> name index
+}
+
+// utf8At returns the UTF-8 constant at an index
+func (cp *constantPool) utf8At(index int) string {
+	return cp.utf8[index]
+}
+
+// classAt returns the internal class name referenced by a class constant
+func (cp *constantPool) classAt(index int) string {
+	return cp.utf8[cp.classes[index]]
+}
+
+// readConstantPool reads the constant pool following the class file header
+func readConstantPool(r *classReader) *constantPool {
+	cp := &constantPool{
+		utf8:    make(map[int]string),
+		classes: make(map[int]int),
+	}
+
+	count := r.u2()
+
+	for i := 1; i < count && r.err == nil; i++ {
+		tag := r.u1()
+
+		switch tag {
+		case cpUtf8:
+			cp.utf8[i] = string(r.bytes(r.u2()))
+		case cpClass:
+			cp.classes[i] = r.u2()
+		case cpString, cpMethodType, cpModule, cpPackage:
+			r.u2()
+		case cpInteger, cpFloat, cpFieldref, cpMethodref, cpInterfaceMethodref, cpNameAndType, cpDynamic, cpInvokeDynamic:
+			r.u4()
+		case cpMethodHandle:
+			r.u1()
+			r.u2()
+		case cpLong, cpDouble:
+			// 8-byte constants take up two entries
+			r.u4()
+			r.u4()
+			i++
+		default:
+			r.err = fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+	}
+
+	return cp
+}
+
+// readMembers reads the fields or methods table as name:descriptor entries
+func readMembers(r *classReader, cp *constantPool) []string {
+	count := r.u2()
+	members := make([]string, 0, count)
+
+	for i := 0; i < count && r.err == nil; i++ {
+		r.u2() // access flags
+		name := cp.utf8At(r.u2())
+		descriptor := cp.utf8At(r.u2())
+
+		// Skip attributes such as Code
+		for a := r.u2(); a > 0 && r.err == nil; a-- {
+			r.u2()
+			r.bytes(r.u4())
+		}
+
+		members = append(members, name+":"+descriptor)
+	}
+
+	sort.Strings(members)
+	return members
+}
+
+// parseClassFile parses the shape of a class from its bytes
+func parseClassFile(data []byte) (*ClassInfo, error) {
+	r := &classReader{data: data}
+
+	if r.u4() != 0xCAFEBABE {
+		return nil, fmt.Errorf("not a class file")
+	}
+
+	r.u2() // minor version
+	r.u2() // major version
+
+	cp := readConstantPool(r)
+
+	r.u2() // access flags
+
+	info := &ClassInfo{
+		Name:       cp.classAt(r.u2()),
+		SuperClass: cp.classAt(r.u2()),
+	}
+
+	for i := r.u2(); i > 0 && r.err == nil; i-- {
+		info.Interfaces = append(info.Interfaces, cp.classAt(r.u2()))
+	}
+
+	info.Fields = readMembers(r, cp)
+	info.Methods = readMembers(r, cp)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	info.References = classReferences(info, cp)
+	return info, nil
+}
+
+// classReferences collects the classes referenced from the constant pool and member descriptors
+func classReferences(info *ClassInfo, cp *constantPool) []string {
+	seen := map[string]bool{info.Name: true}
+	var refs []string
+
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+
+	for index := range cp.classes {
+		name := cp.classAt(index)
+
+		// Array classes are stored as descriptors, e.g. [Lcom/acme/Foo;
+		if strings.HasPrefix(name, "[") {
+			for _, match := range descriptorClassRe.FindAllStringSubmatch(name, -1) {
+				add(match[1])
+			}
+
+			continue
+		}
+
+		add(name)
+	}
+
+	for _, member := range append(append([]string{}, info.Fields...), info.Methods...) {
+		descriptor := member[strings.Index(member, ":")+1:]
+
+		for _, match := range descriptorClassRe.FindAllStringSubmatch(descriptor, -1) {
+			add(match[1])
+		}
+	}
+
+	sort.Strings(refs)
+	return refs
+}
+
+// Schema returns a string that changes when fields, methods or the hierarchy change
+// Method bodies are not part of the schema
+func (c *ClassInfo) Schema() string {
+	return strings.Join([]string{
+		c.Name,
+		c.SuperClass,
+		strings.Join(c.Interfaces, ","),
+		strings.Join(c.Fields, ","),
+		strings.Join(c.Methods, ","),
+	}, "|")
+}
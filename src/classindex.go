@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ClassIndex maps each class to the classes that use it
+// Nested classes are folded into their top-level class, which matches a source file
+type ClassIndex struct {
+	usedBy map[string]map[string]bool
+	tests  map[string]bool
+}
+
+// buildClassIndex reads the compiled classes and test classes of the project
+func buildClassIndex() *ClassIndex {
+	index := &ClassIndex{
+		usedBy: make(map[string]map[string]bool),
+		tests:  make(map[string]bool),
+	}
+
+	index.addClasses(filepath.Join(currentDir, "target", "classes"), false)
+	index.addClasses(filepath.Join(currentDir, "target", "test-classes"), true)
+
+	// Tests that were not compiled yet are still found by name
+	testSources := filepath.Join(currentDir, "src", "test", "java")
+
+	filepath.WalkDir(testSources, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".java") {
+			return nil
+		}
+
+		if name := sourceClassName(testSources, path); name != "" {
+			index.tests[name] = true
+		}
+
+		return nil
+	})
+
+	return index
+}
+
+// addClasses adds the .class files of a directory to the index
+func (idx *ClassIndex) addClasses(dir string, tests bool) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".class") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		info, err := parseClassFile(data)
+		if err != nil {
+			return nil
+		}
+
+		owner := topLevelClass(info.Name)
+
+		if tests {
+			idx.tests[owner] = true
+		}
+
+		for _, ref := range info.References {
+			ref = topLevelClass(ref)
+
+			if ref == owner {
+				continue
+			}
+
+			if idx.usedBy[ref] == nil {
+				idx.usedBy[ref] = make(map[string]bool)
+			}
+
+			idx.usedBy[ref][owner] = true
+		}
+
+		return nil
+	})
+}
+
+// AffectedTests returns the test classes affected by changes to the given classes
+// Tests are found by naming convention and by following the classes that use a changed class
+func (idx *ClassIndex) AffectedTests(changed []string) []string {
+	affected := make(map[string]bool)
+	visited := make(map[string]bool)
+	queue := append([]string{}, changed...)
+
+	for _, class := range changed {
+		visited[class] = true
+
+		pkg, simple := "", class
+		if i := strings.LastIndex(class, "/"); i >= 0 {
+			pkg, simple = class[:i+1], class[i+1:]
+		}
+
+		// A changed test runs itself
+		for _, candidate := range []string{class, class + "Test", class + "Tests", class + "IT", pkg + "Test" + simple} {
+
+			if idx.tests[candidate] {
+				affected[candidate] = true
+			}
+		}
+	}
+
+	// Everything that uses a changed class, directly or indirectly
+	for len(queue) > 0 {
+		class := queue[0]
+		queue = queue[1:]
+
+		for user := range idx.usedBy[class] {
+
+			if visited[user] {
+				continue
+			}
+
+			visited[user] = true
+			queue = append(queue, user)
+
+			if idx.tests[user] {
+				affected[user] = true
+			}
+		}
+	}
+
+	tests := make([]string, 0, len(affected))
+
+	for class := range affected {
+		tests = append(tests, strings.ReplaceAll(class, "/", "."))
+	}
+
+	sort.Strings(tests)
+	return tests
+}
+
+// changedClasses returns the classes of the changed Java sources
+func changedClasses(changes map[string]fsnotify.Op) []string {
+	roots := []string{
+		filepath.Join(currentDir, "src", "main", "java"),
+		filepath.Join(currentDir, "src", "test", "java"),
+	}
+
+	var classes []string
+
+	for path := range changes {
+
+		if !strings.HasSuffix(path, ".java") {
+			continue
+		}
+
+		for _, root := range roots {
+
+			if name := sourceClassName(root, path); name != "" {
+				classes = append(classes, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(classes)
+	return classes
+}
+
+// sourceClassName returns the internal class name of a source file below a source root
+func sourceClassName(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".java")
+}
+
+// topLevelClass returns the outer class of a nested class
+func topLevelClass(name string) string {
+	if i := strings.Index(name, "$"); i > 0 {
+		return name[:i]
+	}
+
+	return name
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -13,62 +12,66 @@ import (
 func installDependencies() {
 	// Run pre-install script
 	if err := runPreScript("install"); err != nil {
-		fmt.Printf("%s✗ Pre-install script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-install script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sInstalling dependencies...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sInstalling dependencies...%s\n", colors.Green, colors.Reset)
 
 	if err := runMvnCommand("dependency:resolve"); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	// Run post-install script
 	if err := runPostScript("install"); err != nil {
-		fmt.Printf("%s✗ Post-install script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-install script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
 // linkProject links the project to local Maven repository
 func linkProject() {
 	if _, err := os.Stat(pomFile); os.IsNotExist(err) {
-		fmt.Printf("%sError: pom.xml not found%s\n", colors.Red, colors.Reset)
-		fmt.Println("Please run 'marn link' from a Maven project directory.")
-		os.Exit(1)
+		fmt.Fprintf(console, "%sError: pom.xml not found%s\n", colors.Red, colors.Reset)
+		fmt.Fprintln(console, "Please run 'marn link' from a Maven project directory.")
+		exitCommand(1)
 	}
 
 	// Run pre-link script
 	if err := runPreScript("link"); err != nil {
-		fmt.Printf("%s✗ Pre-link script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-link script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sLinking project to local Maven repository...%s\n", colors.Blue, colors.Reset)
-	fmt.Println()
+	fmt.Fprintf(console, "%sLinking project to local Maven repository...%s\n", colors.Blue, colors.Reset)
+	fmt.Fprintln(console)
 
-	fmt.Printf("%sInstalling project to ~/.m2/repository...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sInstalling project to ~/.m2/repository...%s\n", colors.Green, colors.Reset)
 
-	if err := runMvnCommand("clean", "install", "-DskipTests"); err != nil {
-		fmt.Printf("%s✗ Failed to link project%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+	args := append(cleanGoal(hasCleanFlag()), "install", "-DskipTests")
+
+	if err := runMvnCommand(args...); err != nil {
+		fmt.Fprintf(console, "%s✗ Failed to link project%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
+	updateBuildHashOrWarn()
+
 	// Update hash after successful link
 	if err := updateSrcHash(currentDir); err != nil {
 		// Log but don't fail the link if hash update fails
-		fmt.Printf("%sWarning: Could not update hash: %v%s\n", colors.Yellow, err, colors.Reset)
+		fmt.Fprintf(console, "%sWarning: Could not update hash: %v%s\n", colors.Yellow, err, colors.Reset)
 	}
 
-	fmt.Println()
-	fmt.Printf("%s✓ Project linked to local Maven repository!%s\n", colors.Green, colors.Reset)
-	fmt.Println()
-	fmt.Println("Other projects can now use this as a dependency.")
+	fmt.Fprintln(console)
+	fmt.Fprintf(console, "%s✓ Project linked to local Maven repository!%s\n", colors.Green, colors.Reset)
+	fmt.Fprintln(console)
+	fmt.Fprintln(console, "Other projects can now use this as a dependency.")
 
 	// Run post-link script
 	if err := runPostScript("link"); err != nil {
-		fmt.Printf("%s✗ Post-link script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-link script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
@@ -76,21 +79,25 @@ func linkProject() {
 func buildProject() {
 	// Run pre-build script
 	if err := runPreScript("build"); err != nil {
-		fmt.Printf("%s✗ Pre-build script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-build script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sBuilding project...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sBuilding project...%s\n", colors.Green, colors.Reset)
 
 	if err := buildLocalDependencies(true); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	// Use package to generate JAR file
-	if err := runMvnCommand("clean", "package", "-DskipTests"); err != nil {
-		os.Exit(1)
+	args := append(cleanGoal(hasCleanFlag()), "package", "-DskipTests")
+
+	if err := runMvnCommand(args...); err != nil {
+		exitCommand(1)
 	}
 
+	updateBuildHashOrWarn()
+
 	// Set TARGET_DIR environment variable
 	targetDir := filepath.Join(currentDir, "target")
 	absTargetDir, err := filepath.Abs(targetDir)
@@ -112,33 +119,42 @@ func buildProject() {
 
 	// Run post-build script
 	if err := runPostScript("build"); err != nil {
-		fmt.Printf("%s✗ Post-build script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-build script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
 // testProject runs tests
 func testProject() {
+	// 'marn test --watch' keeps running the affected tests
+	for i, arg := range os.Args[2:] {
+
+		if arg == "--watch" {
+			testWatchMode(append(append([]string{}, os.Args[2:2+i]...), os.Args[3+i:]...))
+			return
+		}
+	}
+
 	// Run pre-test script
 	if err := runPreScript("test"); err != nil {
-		fmt.Printf("%s✗ Pre-test script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-test script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sRunning tests...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sRunning tests...%s\n", colors.Green, colors.Reset)
 
 	if err := buildLocalDependencies(true); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	if err := runMvnCommand("test"); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	// Run post-test script
 	if err := runPostScript("test"); err != nil {
-		fmt.Printf("%s✗ Post-test script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-test script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
@@ -146,14 +162,14 @@ func testProject() {
 func packageProject() {
 	// Run pre-package script
 	if err := runPreScript("package"); err != nil {
-		fmt.Printf("%s✗ Pre-package script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-package script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sPackaging project...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sPackaging project...%s\n", colors.Green, colors.Reset)
 
 	if err := runMvnCommand("clean", "package"); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	// Set TARGET_DIR environment variable
@@ -165,8 +181,8 @@ func packageProject() {
 
 	// Run post-package script
 	if err := runPostScript("package"); err != nil {
-		fmt.Printf("%s✗ Post-package script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-package script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
@@ -174,52 +190,111 @@ func packageProject() {
 func cleanProject() {
 	// Run pre-clean script
 	if err := runPreScript("clean"); err != nil {
-		fmt.Printf("%s✗ Pre-clean script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-clean script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sCleaning project...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sCleaning project...%s\n", colors.Green, colors.Reset)
 
 	if err := runMvnCommand("clean"); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
 	// Run post-clean script
 	if err := runPostScript("clean"); err != nil {
-		fmt.Printf("%s✗ Post-clean script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Post-clean script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 }
 
 // runProject builds and runs the JAR
+// With --exec, compiles and runs the main class directly from target/classes
 func runProject() {
+	opts, err := parseRunArgs(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(console, "%sError: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
+	}
+
+	runConfig, err := loadRunConfig(opts.Config)
+	if err != nil {
+		fmt.Fprintf(console, "%sError: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
+	}
+
+	if opts.Debug.Enabled {
+		runConfig.JvmArgs = append(runConfig.JvmArgs, opts.Debug.AgentArg())
+	}
+
+	if runConfig.Name != "" {
+		fmt.Fprintf(console, "%sUsing run configuration: %s%s\n", colors.Yellow, runConfig.Name, colors.Reset)
+	}
+
 	// Run pre-run script
 	if err := runPreScript("run"); err != nil {
-		fmt.Printf("%s✗ Pre-run script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%s✗ Pre-run script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
-	fmt.Printf("%sBuilding and running project...%s\n", colors.Green, colors.Reset)
+	fmt.Fprintf(console, "%sBuilding and running project...%s\n", colors.Green, colors.Reset)
 
 	if err := buildLocalDependencies(true); err != nil {
-		os.Exit(1)
+		exitCommand(1)
 	}
 
-	// Get artifact ID and main class
-	artifactID := getArtifactID()
-	mainClass := getMainClass()
+	// Stop the instance started by a previous 'marn run'
+	stopRecordedProcesses("run", getStopGracePeriod())
 
-	// Kill existing processes
-	killExistingProcesses(artifactID, mainClass)
+	var target []string
 
-	// Create data directory if needed
-	os.MkdirAll("data", 0755)
+	if opts.Exec {
+		target = buildExecArgs(opts)
+	} else {
+		target = buildJarArgs(opts)
+	}
 
+	cmd, err := buildJavaCommand(runConfig, target, opts.AppArgs)
+	if err != nil {
+		fmt.Fprintf(console, "%sError: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
+	}
+
+	if opts.Debug.Enabled {
+		printDebugAddress(opts.Debug)
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(console, "%sError: Could not start java: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
+	}
+
+	recordProcess(cmd.Process.Pid, "run", false, cmd.Args)
+
+	cmd.Wait()
+	forgetProcess(cmd.Process.Pid)
+
+	// Run post-run script
+	if err := runPostScript("run"); err != nil {
+		fmt.Fprintf(console, "%s✗ Post-run script failed%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
+	}
+}
+
+// buildJarArgs packages the project and returns the java arguments to run the JAR
+func buildJarArgs(opts RunOptions) []string {
 	// Build the project
-	if err := runMvnCommand("clean", "package", "-DskipTests"); err != nil {
-		os.Exit(1)
+	args := append(cleanGoal(opts.Clean), "package", "-DskipTests")
+
+	if err := runMvnCommand(args...); err != nil {
+		exitCommand(1)
 	}
 
+	updateBuildHashOrWarn()
+
 	// Set TARGET_DIR environment variable
 	targetDir := filepath.Join(currentDir, "target")
 	absTargetDir, err := filepath.Abs(targetDir)
@@ -230,8 +305,8 @@ func runProject() {
 	// Find the JAR file
 	jarFile := findJarFile()
 	if jarFile == "" {
-		fmt.Printf("%sError: No JAR file found in target/ directory%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+		fmt.Fprintf(console, "%sError: No JAR file found in target/ directory%s\n", colors.Red, colors.Reset)
+		exitCommand(1)
 	}
 
 	// Set BUILD_ARTIFACT
@@ -240,28 +315,46 @@ func runProject() {
 		os.Setenv("BUILD_ARTIFACT", absPath)
 	}
 
-	fmt.Printf("%sRunning: %s%s\n", colors.Green, jarFile, colors.Reset)
-	fmt.Println()
+	fmt.Fprintf(console, "%sRunning: %s%s\n", colors.Green, jarFile, colors.Reset)
+	fmt.Fprintln(console)
+
+	return []string{"-jar", jarFile}
+}
 
-	// Run the JAR with additional arguments
-	args := []string{"-jar", jarFile}
-	if len(os.Args) > 2 {
-		args = append(args, os.Args[2:]...)
+// buildExecArgs compiles the project and returns the java arguments to run the main class
+func buildExecArgs(opts RunOptions) []string {
+	mainClass, err := resolveMainClass(opts)
+	if err != nil {
+		fmt.Fprintf(console, "%sError: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
 	}
 
-	cmd := exec.Command("java", args...)
-	cmd.Dir = currentDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	// Compile only, no JAR is needed to run from target/classes
+	args := append(cleanGoal(opts.Clean), "compile")
 
-	cmd.Run()
+	if err := runMvnCommand(args...); err != nil {
+		exitCommand(1)
+	}
 
-	// Run post-run script
-	if err := runPostScript("run"); err != nil {
-		fmt.Printf("%s✗ Post-run script failed%s\n", colors.Red, colors.Reset)
-		os.Exit(1)
+	updateBuildHashOrWarn()
+
+	// Set TARGET_DIR environment variable
+	targetDir := filepath.Join(currentDir, "target")
+	absTargetDir, err := filepath.Abs(targetDir)
+	if err == nil {
+		os.Setenv("TARGET_DIR", absTargetDir)
 	}
+
+	classpath, err := buildExecClasspath()
+	if err != nil {
+		fmt.Fprintf(console, "%sError: Could not resolve classpath: %v%s\n", colors.Red, err, colors.Reset)
+		exitCommand(1)
+	}
+
+	fmt.Fprintf(console, "%sRunning: %s%s\n", colors.Green, mainClass, colors.Reset)
+	fmt.Fprintln(console)
+
+	return []string{"-cp", classpath, mainClass}
 }
 
 // executeScript executes a custom script from pom.xml
@@ -308,6 +401,40 @@ func executeScript(scriptName string) {
 	}
 }
 
+// cleanGoal returns the clean goal when the build has to start from scratch
+// Builds are incremental unless --clean is given, sources were deleted or a pom changed
+func cleanGoal(force bool) []string {
+	if force {
+		return []string{"clean"}
+	}
+
+	if reason := needsClean(currentDir); reason != "" {
+		fmt.Printf("%sClean build: %s%s\n", colors.Yellow, reason, colors.Reset)
+		return []string{"clean"}
+	}
+
+	return nil
+}
+
+// hasCleanFlag checks if --clean was given on the command line
+func hasCleanFlag() bool {
+	for _, arg := range os.Args[2:] {
+
+		if arg == "--clean" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateBuildHashOrWarn records the sources of a successful build for the next clean check
+func updateBuildHashOrWarn() {
+	if err := updateBuildHash(currentDir); err != nil {
+		fmt.Fprintf(console, "%sWarning: Could not update build hash: %v%s\n", colors.Yellow, err, colors.Reset)
+	}
+}
+
 // buildLocalDependencies builds all local dependencies
 func buildLocalDependencies(skipTests bool) error {
 	deps := getLocalDependencies()
@@ -316,7 +443,7 @@ func buildLocalDependencies(skipTests bool) error {
 		return nil
 	}
 
-	fmt.Printf("%sBuilding local dependencies first...%s\n", colors.Yellow, colors.Reset)
+	fmt.Fprintf(console, "%sBuilding local dependencies first...%s\n", colors.Yellow, colors.Reset)
 
 	for _, depPath := range deps {
 		pomPath := filepath.Join(depPath, "pom.xml")
@@ -335,12 +462,12 @@ func buildLocalDependencies(skipTests bool) error {
 			}
 
 			if !shouldRebuild {
-				fmt.Printf("%sSkipping dependency (no changes): %s%s\n", colors.Green, relPath, colors.Reset)
+				fmt.Fprintf(console, "%sSkipping dependency (no changes): %s%s\n", colors.Green, relPath, colors.Reset)
 				printHashComparison(depPath, currentHash, storedHash)
 				continue
 			}
 
-			fmt.Printf("%sBuilding dependency: %s%s\n", colors.Blue, relPath, colors.Reset)
+			fmt.Fprintf(console, "%sBuilding dependency: %s%s\n", colors.Blue, relPath, colors.Reset)
 			printHashComparison(depPath, currentHash, storedHash)
 
 			args := []string{"clean", "install"}
@@ -348,27 +475,23 @@ func buildLocalDependencies(skipTests bool) error {
 				args = append(args, "-DskipTests")
 			}
 
-			cmd := exec.Command("mvn", args...)
-			cmd.Dir = depPath
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			if err := cmd.Run(); err != nil {
-				fmt.Printf("%sFailed to build dependency: %s%s\n", colors.Red, depPath, colors.Reset)
+			// Sibling builds report their diagnostics like the project build
+			if err := runMvnCommandIn(depPath, args...); err != nil {
+				fmt.Fprintf(console, "%sFailed to build dependency: %s%s\n", colors.Red, depPath, colors.Reset)
 				return err
 			}
 
 			// Update hash after successful build
 			if err := updateSrcHash(depPath); err != nil {
 				// Log but don't fail the build if hash update fails
-				fmt.Printf("%sWarning: Could not update hash for %s: %v%s\n", colors.Yellow, depPath, err, colors.Reset)
+				fmt.Fprintf(console, "%sWarning: Could not update hash for %s: %v%s\n", colors.Yellow, depPath, err, colors.Reset)
 			}
 
-			fmt.Printf("%s✓ Dependency built: %s%s\n", colors.Green, relPath, colors.Reset)
+			fmt.Fprintf(console, "%s✓ Dependency built: %s%s\n", colors.Green, relPath, colors.Reset)
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(console)
 	return nil
 }
 
@@ -428,7 +551,7 @@ func runPreScript(commandName string) error {
 	preScriptName := "pre" + strings.ToUpper(commandName[:1]) + commandName[1:]
 
 	if script, exists := scripts[preScriptName]; exists {
-		fmt.Printf("%sRunning pre-script: %s%s\n", colors.Yellow, preScriptName, colors.Reset)
+		fmt.Fprintf(console, "%sRunning pre-script: %s%s\n", colors.Yellow, preScriptName, colors.Reset)
 		return runShellCommand(script)
 	}
 
@@ -441,36 +564,9 @@ func runPostScript(commandName string) error {
 	postScriptName := "post" + strings.ToUpper(commandName[:1]) + commandName[1:]
 
 	if script, exists := scripts[postScriptName]; exists {
-		fmt.Printf("%sRunning post-script: %s%s\n", colors.Yellow, postScriptName, colors.Reset)
+		fmt.Fprintf(console, "%sRunning post-script: %s%s\n", colors.Yellow, postScriptName, colors.Reset)
 		return runShellCommand(script)
 	}
 
 	return nil
 }
-
-// killExistingProcesses kills existing Java processes
-func killExistingProcesses(artifactID, mainClass string) {
-	if isWindows() {
-
-		// On Windows, use taskkill
-		if mainClass != "" {
-			exec.Command("taskkill", "/F", "/FI", fmt.Sprintf("WINDOWTITLE eq *%s*", mainClass)).Run()
-		}
-
-		if artifactID != "" {
-			exec.Command("taskkill", "/F", "/FI", fmt.Sprintf("WINDOWTITLE eq *%s*", artifactID)).Run()
-		}
-	} else {
-
-		// On Unix, use pkill
-		if mainClass != "" {
-			exec.Command("pkill", "-f", fmt.Sprintf("java.*%s", mainClass)).Run()
-		}
-
-		if artifactID != "" {
-			exec.Command("pkill", "-f", fmt.Sprintf("java.*%s.*jar", artifactID)).Run()
-		}
-	}
-
-	time.Sleep(time.Second)
-}
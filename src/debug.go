@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultDebugPort is the JDWP port used when --debug has no port
+const defaultDebugPort = 5005
+
+// DebugOptions holds the JDWP debug agent settings
+type DebugOptions struct {
+	Enabled bool
+	Port    int
+	Suspend bool
+}
+
+// parseDebugSpec parses the value of --debug[=port][,suspend]
+// An empty spec enables debugging on the default port
+func parseDebugSpec(spec string) (DebugOptions, error) {
+	debug := DebugOptions{
+		Enabled: true,
+		Port:    defaultDebugPort,
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+
+		switch part {
+		case "":
+			continue
+		case "suspend":
+			debug.Suspend = true
+		default:
+			port, err := strconv.Atoi(part)
+			if err != nil || port <= 0 || port > 65535 {
+				return debug, fmt.Errorf("invalid debug option '%s', expected --debug[=port][,suspend]", part)
+			}
+
+			debug.Port = port
+		}
+	}
+
+	return debug, nil
+}
+
+// getDebugFromEnv returns the debug options inherited through MARN_DEBUG
+// Watch mode sets it so that 'marn run' in a post command keeps the same port
+func getDebugFromEnv() (DebugOptions, error) {
+	spec, ok := os.LookupEnv("MARN_DEBUG")
+	if !ok {
+		return DebugOptions{}, nil
+	}
+
+	return parseDebugSpec(spec)
+}
+
+// Spec returns the debug options in --debug format
+func (d DebugOptions) Spec() string {
+	spec := strconv.Itoa(d.Port)
+	if d.Suspend {
+		spec += ",suspend"
+	}
+
+	return spec
+}
+
+// Address returns the address the debugger should attach to
+func (d DebugOptions) Address() string {
+	return fmt.Sprintf("localhost:%d", d.Port)
+}
+
+// AgentArg returns the JVM option that loads the JDWP agent
+func (d DebugOptions) AgentArg() string {
+	suspend := "n"
+	if d.Suspend {
+		suspend = "y"
+	}
+
+	return fmt.Sprintf("-agentlib:jdwp=transport=dt_socket,server=y,suspend=%s,address=%s", suspend, d.Address())
+}
+
+// printDebugAddress prints where a debugger can attach
+func printDebugAddress(d DebugOptions) {
+	if d.Suspend {
+		fmt.Fprintf(console, "%sDebugger listening on %s (waiting for debugger to attach)%s\n", colors.Yellow, d.Address(), colors.Reset)
+	} else {
+		fmt.Fprintf(console, "%sDebugger listening on %s%s\n", colors.Yellow, d.Address(), colors.Reset)
+	}
+}
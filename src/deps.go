@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/machinastudios/marn/internal/pomedit"
+)
+
+// Paths of the dependency sections in pom.xml
+const (
+	dependenciesPath        = "project/dependencies"
+	managedDependenciesPath = "project/dependencyManagement/dependencies"
+)
+
+// DependencySpec is a dependency given to 'marn add' or 'marn remove'
+type DependencySpec struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+}
+
+// AddOptions holds the options passed to 'marn add'
+type AddOptions struct {
+	Scope   string
+	Managed bool
+	Specs   []DependencySpec
+}
+
+// String returns the spec as group:artifact:version
+func (d DependencySpec) String() string {
+	name := d.ArtifactID
+
+	if d.GroupID != "" {
+		name = d.GroupID + ":" + name
+	}
+
+	if d.Version != "" {
+		name += ":" + d.Version
+	}
+
+	return name
+}
+
+// parseDependencySpec parses group:artifact@version, group:artifact:version, artifact@version or artifact
+func parseDependencySpec(spec string) (DependencySpec, error) {
+	var dep DependencySpec
+
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		dep.Version = spec[i+1:]
+		spec = spec[:i]
+	}
+
+	parts := strings.Split(spec, ":")
+
+	switch {
+	case len(parts) == 1:
+		dep.ArtifactID = parts[0]
+
+	case len(parts) == 2:
+		dep.GroupID, dep.ArtifactID = parts[0], parts[1]
+
+	case len(parts) == 3 && dep.Version == "":
+		dep.GroupID, dep.ArtifactID, dep.Version = parts[0], parts[1], parts[2]
+
+	default:
+		return dep, fmt.Errorf("invalid dependency '%s', use group:artifact@version", spec)
+	}
+
+	for _, part := range parts {
+
+		if part == "" {
+			return dep, fmt.Errorf("invalid dependency '%s', use group:artifact@version", spec)
+		}
+	}
+
+	return dep, nil
+}
+
+// parseAddArgs parses the arguments given to 'marn add'
+func parseAddArgs(args []string) (AddOptions, error) {
+	var opts AddOptions
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "-D" || arg == "--dev":
+			opts.Scope = "test"
+
+		case arg == "--scope" && i+1 < len(args):
+			opts.Scope = args[i+1]
+			i++
+
+		case strings.HasPrefix(arg, "--scope="):
+			opts.Scope = strings.TrimPrefix(arg, "--scope=")
+
+		case arg == "--managed":
+			opts.Managed = true
+
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown option '%s'", arg)
+
+		default:
+			spec, err := parseDependencySpec(arg)
+			if err != nil {
+				return opts, err
+			}
+
+			opts.Specs = append(opts.Specs, spec)
+		}
+	}
+
+	return opts, nil
+}
+
+// addDependencies implements 'marn add', adding dependencies to pom.xml and installing them
+func addDependencies() {
+	opts, err := parseAddArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	if len(opts.Specs) == 0 {
+		fmt.Printf("%sError: No dependency given%s\n", colors.Red, colors.Reset)
+		fmt.Println("Usage: marn add [-D] [--scope <scope>] [--managed] <group:artifact[@version]>...")
+		os.Exit(1)
+	}
+
+	doc, pom := readPomForEdit()
+
+	// Parent poms only manage versions
+	section := dependenciesPath
+	if opts.Managed || pom.Packaging == "pom" {
+		section = managedDependenciesPath
+	}
+
+	managed := getManagedDependencies()
+	changed := false
+
+	for _, spec := range opts.Specs {
+		ok, err := addDependency(doc, section, spec, opts.Scope, managed)
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+			os.Exit(1)
+		}
+
+		changed = changed || ok
+	}
+
+	if !changed {
+		return
+	}
+
+	writePom(doc)
+	fmt.Println()
+	installDependencies()
+}
+
+// addDependency adds a dependency to a section of pom.xml, or updates its version
+// Returns whether pom.xml changed
+func addDependency(doc *pomedit.Document, section string, spec DependencySpec, scope string, managed map[string]string) (bool, error) {
+	if spec.GroupID == "" {
+		groupID, err := resolveGroupID(spec.ArtifactID, managed)
+		if err != nil {
+			return false, err
+		}
+
+		spec.GroupID = groupID
+	}
+
+	key := spec.GroupID + ":" + spec.ArtifactID
+
+	// An existing dependency only gets a new version
+	elements, existing := findDependencies(doc, section, spec)
+
+	if len(elements) > 0 {
+		if spec.Version == "" || spec.Version == existing[0].Version {
+			fmt.Printf("%s%s is already a dependency%s\n", colors.Yellow, key, colors.Reset)
+			return false, nil
+		}
+
+		if err := setDependencyVersion(doc, elements[0], existing[0], spec.Version); err != nil {
+			return false, err
+		}
+
+		fmt.Printf("%s~ %s %s -> %s%s\n", colors.Yellow, key, existing[0].Version, spec.Version, colors.Reset)
+		return true, nil
+	}
+
+	version := spec.Version
+
+	// Managed dependencies get their version from dependencyManagement
+	_, isManaged := managed[key]
+
+	if version == "" && !(isManaged && section == dependenciesPath) {
+		versions, err := getAvailableVersions(spec.GroupID, spec.ArtifactID)
+		if err != nil {
+			return false, fmt.Errorf("could not look up the versions of %s: %v", key, err)
+		}
+
+		version = latestVersion(versions)
+		if version == "" {
+			return false, fmt.Errorf("no version of %s found, use %s@<version>", key, key)
+		}
+	}
+
+	container, err := doc.Ensure(section)
+	if err != nil {
+		return false, err
+	}
+
+	dependency := container.AppendChild("dependency")
+	dependency.AppendText("groupId", spec.GroupID)
+	dependency.AppendText("artifactId", spec.ArtifactID)
+
+	if version != "" {
+		dependency.AppendText("version", version)
+	}
+
+	if scope != "" {
+		dependency.AppendText("scope", scope)
+	}
+
+	added := key
+	if version != "" {
+		added += ":" + version
+	} else {
+		added += " (managed version)"
+	}
+
+	if scope != "" {
+		added += " (" + scope + ")"
+	}
+
+	fmt.Printf("%s+ %s%s\n", colors.Green, added, colors.Reset)
+	return true, nil
+}
+
+// setDependencyVersion changes the version of a dependency element
+// A version taken from a property changes the property instead
+func setDependencyVersion(doc *pomedit.Document, element *pomedit.Element, dep Dependency, version string) error {
+	if name := dep.VersionProperty(); name != "" {
+		property := doc.First("project/properties/" + name)
+		if property == nil {
+			return fmt.Errorf("property %s of %s:%s is not defined in pom.xml", name, dep.GroupID, dep.ArtifactID)
+		}
+
+		property.SetText(version)
+		return nil
+	}
+
+	if versionElement := element.Child("version"); versionElement != nil {
+		versionElement.SetText(version)
+		return nil
+	}
+
+	element.AppendText("version", version)
+	return nil
+}
+
+// removeDependencies implements 'marn remove', removing dependencies from pom.xml
+func removeDependencies() {
+	var specs []DependencySpec
+
+	for _, arg := range os.Args[2:] {
+		spec, err := parseDependencySpec(arg)
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+			os.Exit(1)
+		}
+
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		fmt.Printf("%sError: No dependency given%s\n", colors.Red, colors.Reset)
+		fmt.Println("Usage: marn remove <artifact|group:artifact>...")
+		os.Exit(1)
+	}
+
+	doc, _ := readPomForEdit()
+
+	for _, spec := range specs {
+
+		if err := removeDependency(doc, spec); err != nil {
+			fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+			os.Exit(1)
+		}
+	}
+
+	writePom(doc)
+	fmt.Println()
+	installDependencies()
+}
+
+// removeDependency removes a dependency from <dependencies>, or from <dependencyManagement> when it is only managed
+func removeDependency(doc *pomedit.Document, spec DependencySpec) error {
+	for _, section := range []string{dependenciesPath, managedDependenciesPath} {
+		elements, deps := findDependencies(doc, section, spec)
+
+		if len(elements) == 0 {
+			continue
+		}
+
+		// An artifact ID alone has to be unambiguous
+		for _, dep := range deps[1:] {
+
+			if dep.GroupID != deps[0].GroupID {
+				return fmt.Errorf("'%s' is ambiguous, use %s:%s or %s:%s", spec.ArtifactID, deps[0].GroupID, spec.ArtifactID, dep.GroupID, spec.ArtifactID)
+			}
+		}
+
+		for _, element := range elements {
+			element.Remove()
+		}
+
+		fmt.Printf("%s- %s:%s%s\n", colors.Red, deps[0].GroupID, deps[0].ArtifactID, colors.Reset)
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a dependency", spec)
+}
+
+// findDependencies returns the dependency elements of a section that match a spec
+func findDependencies(doc *pomedit.Document, section string, spec DependencySpec) ([]*pomedit.Element, []Dependency) {
+	var matches []*pomedit.Element
+	var deps []Dependency
+
+	for _, element := range doc.Find(section + "/dependency") {
+		dep := dependencyFromElement(element)
+
+		if dep.ArtifactID == spec.ArtifactID && (spec.GroupID == "" || dep.GroupID == spec.GroupID) {
+			matches = append(matches, element)
+			deps = append(deps, dep)
+		}
+	}
+
+	return matches, deps
+}
+
+// dependencyFromElement reads a <dependency> element
+func dependencyFromElement(element *pomedit.Element) Dependency {
+	text := func(name string) string {
+		if child := element.Child(name); child != nil {
+			return child.Text()
+		}
+
+		return ""
+	}
+
+	return Dependency{
+		GroupID:    text("groupId"),
+		ArtifactID: text("artifactId"),
+		Version:    text("version"),
+		Scope:      text("scope"),
+	}
+}
+
+// getManagedDependencies returns the versions managed by pom.xml and its local parents
+// Keys are group:artifact
+func getManagedDependencies() map[string]string {
+	managed := make(map[string]string)
+
+	for _, path := range append([]string{pomFile}, getParentPoms()...) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var pom POM
+		if err := xml.Unmarshal(content, &pom); err != nil {
+			continue
+		}
+
+		for _, dep := range pom.DependencyManagement.Dependencies.Dependency {
+			key := dep.GroupID + ":" + dep.ArtifactID
+
+			if _, ok := managed[key]; !ok {
+				managed[key] = dep.Version
+			}
+		}
+	}
+
+	return managed
+}
+
+// resolveGroupID finds the group of an artifact given without one
+// Managed dependencies are checked first, then the local repository
+func resolveGroupID(artifactID string, managed map[string]string) (string, error) {
+	var groups []string
+
+	for key := range managed {
+
+		if strings.HasSuffix(key, ":"+artifactID) {
+			groups = append(groups, strings.TrimSuffix(key, ":"+artifactID))
+		}
+	}
+
+	if len(groups) == 0 {
+		groups = findLocalGroupIDs(artifactID)
+	}
+
+	switch len(groups) {
+	case 0:
+		return "", fmt.Errorf("could not find the group of '%s', use group:%s", artifactID, artifactID)
+	case 1:
+		return groups[0], nil
+	default:
+		return "", fmt.Errorf("'%s' is ambiguous, use one of: %s", artifactID, strings.Join(withSuffix(groups, ":"+artifactID), ", "))
+	}
+}
+
+// withSuffix appends a suffix to every item of a list
+func withSuffix(items []string, suffix string) []string {
+	result := make([]string, len(items))
+
+	for i, item := range items {
+		result[i] = item + suffix
+	}
+
+	return result
+}
+
+// readPomForEdit reads pom.xml for an edit and exits when it can't be parsed
+func readPomForEdit() (*pomedit.Document, POM) {
+	content, err := os.ReadFile(pomFile)
+	if err != nil {
+		fmt.Printf("%sError: Could not read pom.xml: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	var pom POM
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		fmt.Printf("%sError: Could not parse pom.xml: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	doc, err := pomedit.Parse(content)
+	if err != nil {
+		fmt.Printf("%sError: Could not parse pom.xml: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	return doc, pom
+}
+
+// writePom writes the edited pom.xml and exits when it can't be written
+func writePom(doc *pomedit.Document) {
+	if err := writePomFile(pomFile, doc.Bytes()); err != nil {
+		fmt.Printf("%sError: Could not write pom.xml: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+}
+
+// writePomFile writes a pom.xml file, keeping its permissions
+func writePomFile(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return os.WriteFile(path, content, mode)
+}
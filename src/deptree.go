@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DependencyNode is an artifact in the resolved dependency tree
+type DependencyNode struct {
+	GroupID    string            `json:"groupId"`
+	ArtifactID string            `json:"artifactId"`
+	Type       string            `json:"type,omitempty"`
+	Classifier string            `json:"classifier,omitempty"`
+	Version    string            `json:"version"`
+	Scope      string            `json:"scope,omitempty"`
+	Optional   bool              `json:"optional,omitempty"`
+	Omitted    bool              `json:"omitted,omitempty"`   // left out by Maven, Note says why
+	Note       string            `json:"note,omitempty"`      // like "omitted for conflict with 2.0" or "version managed from 1.0"
+	Conflicts  []string          `json:"conflicts,omitempty"` // other versions of the same artifact in the tree
+	Local      string            `json:"local,omitempty"`     // sibling directory marn builds the artifact from
+	Children   []*DependencyNode `json:"children,omitempty"`
+	Parent     *DependencyNode   `json:"-"`
+}
+
+// DependencyTreeCache is the cached output of dependency:tree
+type DependencyTreeCache struct {
+	PomHash string `json:"pomHash"`
+	Tree    string `json:"tree"`
+}
+
+// Key returns the node as group:artifact
+func (n *DependencyNode) Key() string {
+	return n.GroupID + ":" + n.ArtifactID
+}
+
+// String returns the node as group:artifact:version
+func (n *DependencyNode) String() string {
+	return n.Key() + ":" + n.Version
+}
+
+// Path returns the nodes from the root to this node
+func (n *DependencyNode) Path() []*DependencyNode {
+	var path []*DependencyNode
+
+	for node := n; node != nil; node = node.Parent {
+		path = append([]*DependencyNode{node}, path...)
+	}
+
+	return path
+}
+
+// Walk calls fn for the node and all nodes below it, depth first
+func (n *DependencyNode) Walk(fn func(node *DependencyNode)) {
+	fn(n)
+
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// getDependencyTreeCachePath returns the path to the dependency tree cache file
+func getDependencyTreeCachePath(projectPath string) string {
+	return filepath.Join(projectPath, ".marn", "dependency-tree.json")
+}
+
+// calculatePomHash hashes pom.xml, its local parents and the Maven arguments
+// Any of them can change how dependencies resolve
+func calculatePomHash() (string, error) {
+	hasher := sha256.New()
+
+	for _, path := range append([]string{pomFile}, getParentPoms()...) {
+		hash, err := calculateFileHash(path)
+		if err != nil {
+			return "", err
+		}
+
+		hasher.Write([]byte(path + "=" + hash + "\n"))
+	}
+
+	hasher.Write([]byte(strings.Join(getMavenArgs(), " ")))
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// loadDependencyTree returns the resolved dependency tree of the project
+// The output of dependency:tree is cached until pom.xml, its parents or the Maven arguments change
+func loadDependencyTree(refresh bool) (*DependencyNode, error) {
+	pomHash, err := calculatePomHash()
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := getDependencyTreeCachePath(currentDir)
+
+	// Use the cached tree if nothing changed
+	if data, err := os.ReadFile(cachePath); err == nil && !refresh {
+		var cache DependencyTreeCache
+
+		if err := json.Unmarshal(data, &cache); err == nil && cache.PomHash == pomHash {
+			return parseDependencyTree(cache.Tree)
+		}
+	}
+
+	fmt.Printf("%sResolving dependency tree...%s\n", colors.Green, colors.Reset)
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, err
+	}
+
+	outputFile := filepath.Join(currentDir, ".marn", "dependency-tree.txt")
+	defer os.Remove(outputFile)
+
+	// Verbose output keeps the artifacts Maven omitted for conflicts and duplicates
+	if err := runMvnCommand("-q", "dependency:tree", "-Dverbose", "-DoutputType=text", "-DoutputFile="+outputFile); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseDependencyTree(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	cache := DependencyTreeCache{
+		PomHash: pomHash,
+		Tree:    string(content),
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// parseDependencyTree parses the text output of dependency:tree
+// Lines look like "|  +- group:artifact:jar:1.0:compile" and every level adds three characters
+func parseDependencyTree(text string) (*DependencyNode, error) {
+	var root *DependencyNode
+	var stack []*DependencyNode
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Skip the tree drawing to find the depth
+		depth := 0
+		for strings.HasPrefix(line[depth*3:], "|  ") || strings.HasPrefix(line[depth*3:], "   ") {
+			depth++
+		}
+
+		rest := line[depth*3:]
+
+		if strings.HasPrefix(rest, "+- ") || strings.HasPrefix(rest, "\\- ") {
+			depth++
+			rest = rest[3:]
+		}
+
+		node, err := parseDependencyNode(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if depth == 0 {
+
+			if root != nil {
+				return nil, fmt.Errorf("dependency tree has more than one root: %s", rest)
+			}
+
+			root = node
+			stack = []*DependencyNode{root}
+			continue
+		}
+
+		if depth > len(stack) {
+			return nil, fmt.Errorf("unexpected indentation in dependency tree: %s", line)
+		}
+
+		node.Parent = stack[depth-1]
+		node.Parent.Children = append(node.Parent.Children, node)
+		stack = append(stack[:depth], node)
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("empty dependency tree")
+	}
+
+	return root, nil
+}
+
+// parseDependencyNode parses an artifact of the tree
+// Omitted artifacts are wrapped in parentheses, like "(g:a:jar:1.0:compile - omitted for duplicate)"
+func parseDependencyNode(text string) (*DependencyNode, error) {
+	node := &DependencyNode{}
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		node.Omitted = true
+		text = text[1 : len(text)-1]
+
+		if i := strings.Index(text, " - "); i >= 0 {
+			node.Note = text[i+3:]
+			text = text[:i]
+		}
+	}
+
+	// Notes of included artifacts follow in parentheses, like " (version managed from 1.0)"
+	for {
+		i := strings.LastIndex(text, " (")
+		if i < 0 || !strings.HasSuffix(text, ")") {
+			break
+		}
+
+		note := text[i+2 : len(text)-1]
+		text = text[:i]
+
+		if note == "optional" {
+			node.Optional = true
+			continue
+		}
+
+		node.Note = strings.TrimPrefix(strings.TrimSpace(node.Note+"; "+note), "; ")
+	}
+
+	parts := strings.Split(strings.TrimSpace(text), ":")
+
+	switch len(parts) {
+	case 4:
+		// The root has no scope
+		node.GroupID, node.ArtifactID, node.Type, node.Version = parts[0], parts[1], parts[2], parts[3]
+
+	case 5:
+		node.GroupID, node.ArtifactID, node.Type, node.Version, node.Scope = parts[0], parts[1], parts[2], parts[3], parts[4]
+
+	case 6:
+		node.GroupID, node.ArtifactID, node.Type, node.Classifier, node.Version, node.Scope = parts[0], parts[1], parts[2], parts[3], parts[4], parts[5]
+
+	default:
+		return nil, fmt.Errorf("could not parse dependency '%s'", text)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Diagnostic is a single compiler error, warning or test failure
+type Diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Source   string `json:"source"`
+}
+
+// Location returns the clickable path:line:col of a diagnostic
+func (d Diagnostic) Location() string {
+	switch {
+	case d.File == "":
+		return ""
+	case d.Line == 0:
+		return d.File
+	case d.Column == 0:
+		return fmt.Sprintf("%s:%d", d.File, d.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+}
+
+// Output formats for diagnostics
+const (
+	formatJSON   = "json"
+	formatSARIF  = "sarif"
+	formatGitHub = "github"
+)
+
+// Diagnostics output selected with --format
+var (
+	outputFormat string
+
+	// console receives the messages and Maven output of commands that support --format
+	// JSON and SARIF documents own stdout, so everything else goes to stderr
+	console = os.Stdout
+
+	// reportedDiagnostics are collected from every Maven build of the command, including local dependencies
+	reportedDiagnostics []Diagnostic
+)
+
+var (
+	// compilerDiagnosticRe matches "[ERROR] /path/Foo.java:[12,5] cannot find symbol"
+	compilerDiagnosticRe = regexp.MustCompile(`^\[(ERROR|WARNING)\] (.+\.\w+):\[(\d+),(\d+)\] (.*)$`)
+
+	// mavenBoilerplate are [ERROR] lines that never explain a failure
+	mavenBoilerplate = []string{
+		"COMPILATION ERROR", "-> [Help", "[Help ", "To see the full stack trace", "Re-run Maven using",
+		"For more information about the errors", "Failures:", "Errors:", "Tests run:",
+	}
+)
+
+// DiagnosticCollector turns Maven output into diagnostics
+type DiagnosticCollector struct {
+	specific []Diagnostic
+	generic  []Diagnostic
+	seen     map[string]bool
+	last     int // index of the diagnostic that continuation lines belong to, -1 for none
+}
+
+// newDiagnosticCollector creates an empty collector
+func newDiagnosticCollector() *DiagnosticCollector {
+	return &DiagnosticCollector{
+		seen: make(map[string]bool),
+		last: -1,
+	}
+}
+
+// AddLine parses one line of Maven output
+func (c *DiagnosticCollector) AddLine(line string) {
+
+	// Indented lines such as "  symbol: variable x" belong to the previous diagnostic
+	if c.last >= 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+		d := &c.specific[c.last]
+		d.Detail = strings.TrimPrefix(d.Detail+"\n"+strings.TrimSpace(line), "\n")
+		return
+	}
+
+	c.last = -1
+
+	if match := compilerDiagnosticRe.FindStringSubmatch(line); match != nil {
+		lineNo, _ := strconv.Atoi(match[3])
+		column, _ := strconv.Atoi(match[4])
+
+		d := Diagnostic{
+			File:     relativeToProject(match[2]),
+			Line:     lineNo,
+			Column:   column,
+			Severity: strings.ToLower(match[1]),
+			Message:  match[5],
+			Source:   "compiler",
+		}
+
+		// Maven repeats compiler errors in its failure summary
+		if c.add(d) {
+			c.last = len(c.specific) - 1
+		}
+
+		return
+	}
+
+	if !strings.HasPrefix(line, "[ERROR]") {
+		return
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(line, "[ERROR]"))
+	if text == "" {
+		return
+	}
+
+	for _, prefix := range mavenBoilerplate {
+
+		if strings.HasPrefix(text, prefix) {
+			return
+		}
+	}
+
+	if !c.seen[text] {
+		c.seen[text] = true
+		c.generic = append(c.generic, Diagnostic{Severity: "error", Message: text, Source: "maven"})
+	}
+}
+
+// add adds a specific diagnostic unless it is a duplicate
+func (c *DiagnosticCollector) add(d Diagnostic) bool {
+	key := d.Location() + "|" + d.Severity + "|" + d.Message
+
+	if c.seen[key] {
+		return false
+	}
+
+	c.seen[key] = true
+	c.specific = append(c.specific, d)
+	return true
+}
+
+// AddTestReports adds the test failures from surefire reports written since a point in time
+func (c *DiagnosticCollector) AddTestReports(since time.Time) {
+	for _, failure := range readSurefireReports(since).Failed {
+		file, line := testFailureLocation(failure)
+
+		message := strings.TrimSpace(strings.SplitN(failure.Message, "\n", 2)[0])
+		if message == "" {
+			message = "test failed"
+		}
+
+		c.add(Diagnostic{
+			File:     file,
+			Line:     line,
+			Severity: "error",
+			Message:  failure.Name + ": " + message,
+			Source:   "test",
+		})
+	}
+}
+
+// Diagnostics returns compiler and test diagnostics, or the Maven errors when there are none
+func (c *DiagnosticCollector) Diagnostics() []Diagnostic {
+	for _, d := range c.specific {
+
+		if d.Severity == "error" {
+			return c.specific
+		}
+	}
+
+	return append(append([]Diagnostic{}, c.specific...), c.generic...)
+}
+
+// AddFile parses a saved Maven log
+func (c *DiagnosticCollector) AddFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		c.AddLine(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// testFailureLocation finds the test source and line of a failure from its stack trace
+func testFailureLocation(failure TestFailure) (string, int) {
+	className := failure.ClassName
+
+	frameRe := regexp.MustCompile(`at ` + regexp.QuoteMeta(className) + `\.[\w$<>]+\(([\w$]+\.\w+):(\d+)\)`)
+	match := frameRe.FindStringSubmatch(failure.Trace)
+
+	pkgPath := ""
+	if i := strings.LastIndex(className, "."); i >= 0 {
+		pkgPath = strings.ReplaceAll(className[:i], ".", "/")
+	}
+
+	fileName := className[strings.LastIndex(className, ".")+1:] + ".java"
+	line := 0
+
+	if match != nil {
+		fileName = match[1]
+		line, _ = strconv.Atoi(match[2])
+	}
+
+	file := filepath.Join("src", "test", "java", filepath.FromSlash(pkgPath), fileName)
+	if _, err := os.Stat(filepath.Join(currentDir, file)); err != nil {
+		return "", 0
+	}
+
+	return filepath.ToSlash(file), line
+}
+
+// relativeToProject makes paths inside the project relative
+func relativeToProject(path string) string {
+	if rel, err := filepath.Rel(currentDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+
+	return path
+}
+
+// lineWriter is an io.Writer that calls a function for every complete line
+// Color codes are removed from the lines
+type lineWriter struct {
+	mu      sync.Mutex
+	partial []byte
+	line    func(string)
+}
+
+// newLineWriter creates a writer that splits its input into lines
+func newLineWriter(line func(string)) *lineWriter {
+	return &lineWriter{line: line}
+}
+
+// Write splits the written bytes into lines
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.line(stripColors(strings.TrimSuffix(string(w.partial[:i]), "\r")))
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush passes on a last line without a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.line(stripColors(string(w.partial)))
+		w.partial = nil
+	}
+}
+
+// parseFormatFlag removes --format from the arguments and sets up the diagnostics output
+// JSON and SARIF documents own stdout, so everything else is printed to stderr
+func parseFormatFlag(args []string) ([]string, error) {
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			rest = append(rest, args[i:]...)
+			i = len(args)
+			continue
+
+		case arg == "--format" && i+1 < len(args):
+			outputFormat = args[i+1]
+			i++
+
+		case strings.HasPrefix(arg, "--format="):
+			outputFormat = strings.TrimPrefix(arg, "--format=")
+
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		if outputFormat != formatJSON && outputFormat != formatSARIF && outputFormat != formatGitHub {
+			return nil, fmt.Errorf("unknown format '%s', use json, sarif or github", outputFormat)
+		}
+	}
+
+	if outputFormat == formatJSON || outputFormat == formatSARIF {
+		console = os.Stderr
+	}
+
+	return rest, nil
+}
+
+// reportDiagnostics adds the diagnostics of a Maven build to the --format output
+func reportDiagnostics(diagnostics []Diagnostic) {
+	reportedDiagnostics = append(reportedDiagnostics, diagnostics...)
+}
+
+// exitCommand writes the --format output and exits
+// Commands that support --format end here, so the output is written exactly once, also on failure
+func exitCommand(code int) {
+
+	if outputFormat != "" {
+
+		if err := writeDiagnostics(os.Stdout, outputFormat, code == 0, reportedDiagnostics); err != nil {
+			fmt.Fprintf(console, "%sWarning: Could not write diagnostics: %v%s\n", colors.Yellow, err, colors.Reset)
+		}
+	}
+
+	os.Exit(code)
+}
+
+// printDiagnostics prints diagnostics grouped by file with a summary line
+func printDiagnostics(diagnostics []Diagnostic) {
+	var files []string
+	groups := make(map[string][]Diagnostic)
+
+	for _, d := range diagnostics {
+
+		if _, exists := groups[d.File]; !exists {
+			files = append(files, d.File)
+		}
+
+		groups[d.File] = append(groups[d.File], d)
+	}
+
+	errors, warnings := 0, 0
+
+	for _, file := range files {
+
+		if file != "" {
+			fmt.Printf("%s%s%s\n", colors.Blue, file, colors.Reset)
+		}
+
+		for _, d := range groups[file] {
+			color := colors.Red
+			if d.Severity == "warning" {
+				color = colors.Yellow
+				warnings++
+			} else {
+				errors++
+			}
+
+			if location := d.Location(); location != "" {
+				fmt.Printf("  %s: %s%s:%s %s\n", location, color, d.Severity, colors.Reset, d.Message)
+			} else {
+				fmt.Printf("  %s%s:%s %s\n", color, d.Severity, colors.Reset, d.Message)
+			}
+
+			for _, detail := range strings.Split(d.Detail, "\n") {
+
+				if detail != "" {
+					fmt.Printf("      %s\n", detail)
+				}
+			}
+		}
+	}
+
+	fileCount := len(files)
+	if groups[""] != nil {
+		fileCount--
+	}
+
+	if errors > 0 || warnings > 0 {
+		fmt.Printf("%s%d error(s), %d warning(s) in %d file(s)%s\n", colors.Red, errors, warnings, fileCount, colors.Reset)
+	}
+}
+
+// writeDiagnostics writes diagnostics in a machine readable format
+func writeDiagnostics(w io.Writer, format string, success bool, diagnostics []Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(map[string]interface{}{
+			"success":     success,
+			"diagnostics": diagnostics,
+		})
+
+	case formatSARIF:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(sarifLog(diagnostics))
+
+	case formatGitHub:
+		for _, d := range diagnostics {
+			var props []string
+
+			if d.File != "" {
+				props = append(props, "file="+escapeGitHubProperty(d.File))
+			}
+
+			if d.Line > 0 {
+				props = append(props, "line="+strconv.Itoa(d.Line))
+			}
+
+			if d.Column > 0 {
+				props = append(props, "col="+strconv.Itoa(d.Column))
+			}
+
+			message := d.Message
+			if d.Detail != "" {
+				message += "\n" + d.Detail
+			}
+
+			fmt.Fprintf(w, "::%s %s::%s\n", d.Severity, strings.Join(props, ","), escapeGitHubData(message))
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown format '%s'", format)
+}
+
+// sarifLog builds a SARIF 2.1.0 log from diagnostics
+func sarifLog(diagnostics []Diagnostic) map[string]interface{} {
+	results := make([]map[string]interface{}, 0, len(diagnostics))
+
+	for _, d := range diagnostics {
+		message := d.Message
+		if d.Detail != "" {
+			message += "\n" + d.Detail
+		}
+
+		result := map[string]interface{}{
+			"ruleId":  d.Source,
+			"level":   d.Severity,
+			"message": map[string]string{"text": message},
+		}
+
+		if d.File != "" {
+			region := map[string]int{}
+
+			if d.Line > 0 {
+				region["startLine"] = d.Line
+			}
+
+			if d.Column > 0 {
+				region["startColumn"] = d.Column
+			}
+
+			location := map[string]interface{}{
+				"artifactLocation": map[string]string{"uri": filepath.ToSlash(d.File)},
+			}
+
+			if len(region) > 0 {
+				location["region"] = region
+			}
+
+			result["locations"] = []map[string]interface{}{{"physicalLocation": location}}
+		}
+
+		results = append(results, result)
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]interface{}{{
+			"tool": map[string]interface{}{
+				"driver": map[string]string{
+					"name":           "marn",
+					"version":        Version,
+					"informationUri": "https://github.com/machinastudios/marn",
+				},
+			},
+			"results": results,
+		}},
+	}
+}
+
+// escapeGitHubData escapes a workflow command message
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a workflow command property value
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
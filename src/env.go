@@ -5,21 +5,85 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
+// envFileValues holds the variables that were set from the .env file
+// They may be replaced when the file is reloaded, other environment variables are never overridden
+var envFileValues = make(map[string]string)
+
 // loadEnvFile loads environment variables from .env file if it exists
 func loadEnvFile() error {
-	envPath := filepath.Join(currentDir, ".env")
+	values, err := readEnvFile(filepath.Join(currentDir, ".env"))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+
+		// Set environment variable (don't override existing)
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+			envFileValues[key] = value
+		}
+	}
+
+	return nil
+}
+
+// reloadEnvFile applies the current .env file and returns the names of the variables that changed
+// Variables removed from the file are unset again
+func reloadEnvFile() ([]string, error) {
+	values, err := readEnvFile(filepath.Join(currentDir, ".env"))
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+
+	for key := range envFileValues {
+
+		if _, exists := values[key]; !exists {
+			os.Unsetenv(key)
+			delete(envFileValues, key)
+			changed = append(changed, key)
+		}
+	}
+
+	for key, value := range values {
+		old, fromFile := envFileValues[key]
+
+		if fromFile && old == value {
+			continue
+		}
+
+		// Variables set outside of .env still win
+		if !fromFile && os.Getenv(key) != "" {
+			continue
+		}
+
+		os.Setenv(key, value)
+		envFileValues[key] = value
+		changed = append(changed, key)
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// readEnvFile parses a .env file into a map, a missing file is empty
+func readEnvFile(envPath string) (map[string]string, error) {
+	values := make(map[string]string)
 
 	// Check if .env file exists
 	if _, err := os.Stat(envPath); os.IsNotExist(err) {
-		return nil
+		return values, nil
 	}
 
 	file, err := os.Open(envPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
@@ -43,14 +107,11 @@ func loadEnvFile() error {
 				value = value[1 : len(value)-1]
 			}
 
-			// Set environment variable (don't override existing)
-			if os.Getenv(key) == "" {
-				os.Setenv(key, value)
-			}
+			values[key] = value
 		}
 	}
 
-	return scanner.Err()
+	return values, scanner.Err()
 }
 
 // expandEnvVars expands environment variables in the format ${VAR} or $VAR
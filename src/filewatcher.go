@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultPollInterval is how often the polling fallback scans the watched trees
+const defaultPollInterval = time.Second
+
+// FileWatcher watches directory trees, including directories created later
+// It uses fsnotify and falls back to polling when the OS watch limit is reached
+type FileWatcher struct {
+	Events chan fsnotify.Event
+	Errors chan error
+
+	// SkipDir excludes directories from watching, e.g. ignored build output
+	SkipDir func(path string) bool
+
+	mu       sync.Mutex
+	notify   *fsnotify.Watcher
+	roots    map[string]bool // root -> recursive
+	watched  map[string]bool
+	polling  bool
+	files    map[string]os.FileInfo
+	interval time.Duration
+	done     chan struct{}
+}
+
+// newFileWatcher creates a file watcher
+func newFileWatcher() (*FileWatcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &FileWatcher{
+		Events:   make(chan fsnotify.Event, 100),
+		Errors:   make(chan error, 10),
+		notify:   notify,
+		roots:    make(map[string]bool),
+		watched:  make(map[string]bool),
+		interval: defaultPollInterval,
+		done:     make(chan struct{}),
+	}
+
+	go w.forwardEvents()
+
+	return w, nil
+}
+
+// AddTree watches a directory and all its subdirectories
+func (w *FileWatcher) AddTree(dir string) error {
+	return w.addRoot(dir, true)
+}
+
+// AddDir watches the files directly inside a directory
+func (w *FileWatcher) AddDir(dir string) error {
+	return w.addRoot(dir, false)
+}
+
+// addRoot starts watching a root directory
+func (w *FileWatcher) addRoot(dir string, recursive bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// A recursive watch also covers the directory itself
+	if w.roots[dir] {
+		return nil
+	}
+
+	w.roots[dir] = recursive
+
+	// Existing files of a new root are not reported as created
+	if w.polling {
+		for path, info := range w.scanLocked() {
+
+			if _, known := w.files[path]; !known {
+				w.files[path] = info
+			}
+		}
+
+		return nil
+	}
+
+	if !recursive {
+		if w.watched[dir] {
+			return nil
+		}
+
+		return w.addWatchLocked(dir)
+	}
+
+	_, err := w.addTreeLocked(dir, false)
+	return err
+}
+
+// addWatchLocked adds a single directory watch, switching to polling at the watch limit
+func (w *FileWatcher) addWatchLocked(dir string) error {
+	if err := w.notify.Add(dir); err != nil {
+
+		if isWatchLimitError(err) {
+			w.startPollingLocked(err)
+			return nil
+		}
+
+		return err
+	}
+
+	w.watched[dir] = true
+	return nil
+}
+
+// inRecursiveRoot checks if a path is inside a recursively watched root
+func (w *FileWatcher) inRecursiveRoot(path string) bool {
+	for root, recursive := range w.roots {
+
+		if recursive && (path == root || strings.HasPrefix(path, root+string(os.PathSeparator))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipped checks if a directory is excluded by SkipDir
+// Roots are always watched
+func (w *FileWatcher) skipped(path string) bool {
+	if _, isRoot := w.roots[path]; isRoot {
+		return false
+	}
+
+	return w.SkipDir != nil && w.SkipDir(path)
+}
+
+// RemoveTree stops watching a directory and all its subdirectories
+func (w *FileWatcher) RemoveTree(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.roots, dir)
+	w.removeTreeLocked(dir)
+
+	// Files of a removed root are not reported as deleted
+	prefix := dir + string(os.PathSeparator)
+
+	for path := range w.files {
+
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(w.files, path)
+		}
+	}
+}
+
+// Close stops the watcher
+func (w *FileWatcher) Close() {
+	close(w.done)
+	w.notify.Close()
+}
+
+// addTreeLocked adds watches for a tree, optionally returning Create events for files already in it
+// Files in a directory created after startup may be written before its watch exists
+func (w *FileWatcher) addTreeLocked(dir string, reportFiles bool) ([]fsnotify.Event, error) {
+	var events []fsnotify.Event
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The tree may change while it is walked
+			return nil
+		}
+
+		if !d.IsDir() {
+			if reportFiles {
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			}
+
+			return nil
+		}
+
+		if w.skipped(path) {
+			return filepath.SkipDir
+		}
+
+		if w.watched[path] {
+			return nil
+		}
+
+		if err := w.addWatchLocked(path); err != nil {
+			return err
+		}
+
+		if w.polling {
+			return filepath.SkipAll
+		}
+
+		return nil
+	})
+
+	return events, err
+}
+
+// removeTreeLocked removes the watches for a tree
+func (w *FileWatcher) removeTreeLocked(dir string) {
+	prefix := dir + string(os.PathSeparator)
+
+	for path := range w.watched {
+
+		if path == dir || strings.HasPrefix(path, prefix) {
+			w.notify.Remove(path)
+			delete(w.watched, path)
+		}
+	}
+}
+
+// forwardEvents passes fsnotify events on and keeps the watch set in sync
+func (w *FileWatcher) forwardEvents() {
+	for {
+		select {
+		case event, ok := <-w.notify.Events:
+
+			if !ok {
+				return
+			}
+
+			w.mu.Lock()
+
+			var discovered []fsnotify.Event
+
+			// New directories, including ones moved in, are watched right away
+			if event.Op&fsnotify.Create != 0 && !w.polling && w.inRecursiveRoot(event.Name) {
+
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					discovered, _ = w.addTreeLocked(event.Name, true)
+				}
+			}
+
+			// Deleted or moved-away directories are no longer watched
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && w.watched[event.Name] {
+				w.removeTreeLocked(event.Name)
+			}
+
+			w.mu.Unlock()
+			w.emit(event)
+
+			for _, e := range discovered {
+				w.emit(e)
+			}
+
+		case err, ok := <-w.notify.Errors:
+
+			if !ok {
+				return
+			}
+
+			w.Errors <- err
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// emit sends an event unless the watcher is closed
+func (w *FileWatcher) emit(event fsnotify.Event) {
+	select {
+	case w.Events <- event:
+	case <-w.done:
+	}
+}
+
+// isWatchLimitError checks if an error means the OS ran out of watches
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// startPollingLocked switches from fsnotify to scanning the trees periodically
+func (w *FileWatcher) startPollingLocked(cause error) {
+	if w.polling {
+		return
+	}
+
+	w.polling = true
+
+	fmt.Printf("%sWarning: File watch limit reached (%v)%s\n", colors.Yellow, cause, colors.Reset)
+	fmt.Printf("%sFalling back to polling every %v. To watch natively, raise the limit, e.g.:%s\n", colors.Yellow, w.interval, colors.Reset)
+	fmt.Printf("%s  sudo sysctl fs.inotify.max_user_watches=524288%s\n", colors.Yellow, colors.Reset)
+
+	for path := range w.watched {
+		w.notify.Remove(path)
+	}
+
+	w.watched = make(map[string]bool)
+	w.files = w.scanLocked()
+
+	go w.poll()
+}
+
+// scanLocked records the state of every file and directory in the watched trees
+func (w *FileWatcher) scanLocked() map[string]os.FileInfo {
+	files := make(map[string]os.FileInfo)
+
+	for root, recursive := range w.roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if d.IsDir() && path != root && (!recursive || w.skipped(path)) {
+				return filepath.SkipDir
+			}
+
+			if info, err := d.Info(); err == nil {
+				files[path] = info
+			}
+
+			return nil
+		})
+	}
+
+	return files
+}
+
+// poll scans the watched trees and emits events for differences
+func (w *FileWatcher) poll() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+
+		w.mu.Lock()
+		previous := w.files
+		current := w.scanLocked()
+		w.files = current
+		w.mu.Unlock()
+
+		for path, info := range current {
+			old, existed := previous[path]
+
+			switch {
+			case !existed:
+				w.emit(fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case !info.IsDir() && (old.Size() != info.Size() || !old.ModTime().Equal(info.ModTime())):
+				w.emit(fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+
+		for path := range previous {
+
+			if _, exists := current[path]; !exists {
+				w.emit(fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			}
+		}
+	}
+}
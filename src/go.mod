@@ -2,6 +2,7 @@ module github.com/machinastudios/marn
 
 go 1.21
 
-require github.com/fsnotify/fsnotify v1.8.0
-
-require golang.org/x/sys v0.13.0 // indirect
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	golang.org/x/sys v0.13.0
+)
@@ -9,6 +9,7 @@ import (
     "os"
     "path/filepath"
     "sort"
+    "strings"
 )
 
 // HashStore stores the hash information for a project
@@ -23,13 +24,22 @@ func getHashFilePath(projectPath string) string {
     return filepath.Join(projectPath, ".marn", "src-hash.json")
 }
 
+// getBuildHashFilePath returns the path to the hash of the last build of a project
+// It is kept apart from src-hash.json, which tracks what was installed for dependent projects
+func getBuildHashFilePath(projectPath string) string {
+    return filepath.Join(projectPath, ".marn", "build-hash.json")
+}
+
 // calculateSrcHash calculates the hash of all source files in src/ directory
 func calculateSrcHash(projectPath string) (string, map[string]string, error) {
-    srcDirs := []string{
+    return calculateDirsHash(projectPath, []string{
         filepath.Join(projectPath, "src", "main", "java"),
         filepath.Join(projectPath, "src", "main", "resources"),
-    }
+    })
+}
 
+// calculateDirsHash calculates the hash of all files in a list of directories
+func calculateDirsHash(projectPath string, srcDirs []string) (string, map[string]string, error) {
     hasher := sha256.New()
     fileHashes := make(map[string]string)
     var allFilePaths []string
@@ -99,8 +109,11 @@ func calculateFileHash(filePath string) (string, error) {
 
 // loadHashStore loads the hash store from disk
 func loadHashStore(projectPath string) (*HashStore, error) {
-    hashFilePath := getHashFilePath(projectPath)
+    return readHashStore(getHashFilePath(projectPath), projectPath)
+}
 
+// readHashStore loads a hash store file of a project
+func readHashStore(hashFilePath string, projectPath string) (*HashStore, error) {
     // If file doesn't exist, return empty store
     if _, err := os.Stat(hashFilePath); os.IsNotExist(err) {
         return &HashStore{
@@ -125,8 +138,11 @@ func loadHashStore(projectPath string) (*HashStore, error) {
 
 // saveHashStore saves the hash store to disk
 func saveHashStore(store *HashStore) error {
-    hashFilePath := getHashFilePath(store.ProjectPath)
+    return writeHashStore(getHashFilePath(store.ProjectPath), store)
+}
 
+// writeHashStore saves a hash store file
+func writeHashStore(hashFilePath string, store *HashStore) error {
     // Create .marn directory if it doesn't exist
     hashDir := filepath.Dir(hashFilePath)
     if err := os.MkdirAll(hashDir, 0755); err != nil {
@@ -181,6 +197,98 @@ func updateSrcHash(projectPath string) error {
     return saveHashStore(store)
 }
 
+// calculateBuildHash calculates the hash of everything that ends up in target/
+// Test sources and the pom files are included, unlike the source hash
+func calculateBuildHash(projectPath string) (string, map[string]string, error) {
+    currentHash, fileHashes, err := calculateDirsHash(projectPath, []string{
+        filepath.Join(projectPath, "src", "main", "java"),
+        filepath.Join(projectPath, "src", "main", "resources"),
+        filepath.Join(projectPath, "src", "test", "java"),
+        filepath.Join(projectPath, "src", "test", "resources"),
+    })
+    if err != nil {
+        return "", nil, err
+    }
+
+    for _, pom := range append([]string{pomFile}, getParentPoms()...) {
+        pomHash, err := calculateFileHash(pom)
+        if err != nil {
+            continue
+        }
+
+        relPath, err := filepath.Rel(projectPath, pom)
+        if err != nil {
+            relPath = pom
+        }
+
+        fileHashes[relPath] = pomHash
+    }
+
+    return currentHash, fileHashes, nil
+}
+
+// needsClean returns why the project needs a clean build, or "" for an incremental build
+// Deleted sources leave stale classes in target/ and pom changes can change the whole build
+func needsClean(projectPath string) string {
+    if _, err := os.Stat(filepath.Join(projectPath, "target")); os.IsNotExist(err) {
+        return ""
+    }
+
+    store, err := readHashStore(getBuildHashFilePath(projectPath), projectPath)
+    if err != nil || store.SrcHash == "" {
+        return "no previous build recorded"
+    }
+
+    _, fileHashes, err := calculateBuildHash(projectPath)
+    if err != nil {
+        return "could not hash the sources"
+    }
+
+    var deleted []string
+
+    for relPath, storedHash := range store.Files {
+
+        // Everything outside src/ is a pom file
+        if !strings.HasPrefix(filepath.ToSlash(relPath), "src/") {
+
+            if fileHashes[relPath] != storedHash {
+                return relPath + " changed"
+            }
+
+            continue
+        }
+
+        if _, exists := fileHashes[relPath]; !exists {
+            deleted = append(deleted, relPath)
+        }
+    }
+
+    switch len(deleted) {
+    case 0:
+        return ""
+    case 1:
+        return deleted[0] + " was deleted"
+    default:
+        return fmt.Sprintf("%d files were deleted", len(deleted))
+    }
+}
+
+// updateBuildHash records the sources of a successful build
+func updateBuildHash(projectPath string) error {
+    currentHash, fileHashes, err := calculateBuildHash(projectPath)
+    if err != nil {
+        return err
+    }
+
+    store := &HashStore{
+        ProjectPath: projectPath,
+        SrcHash:     currentHash,
+        Files:       fileHashes,
+    }
+
+    return writeHashStore(getBuildHashFilePath(projectPath), store)
+}
+
 // getSrcHashDisplay returns a short hash for display purposes
 func getSrcHashDisplay(hash string) string {
     if hash == "" {
@@ -224,10 +332,10 @@ func shouldRebuildDependency(projectPath string) (bool, string, string, error) {
 // printHashComparison prints a comparison of hashes for debugging
 func printHashComparison(projectPath string, currentHash, storedHash string) {
     if storedHash == "" {
-        fmt.Printf("%s  Hash: %s (new)%s\n", colors.Yellow, getSrcHashDisplay(currentHash), colors.Reset)
+        fmt.Fprintf(console, "%s  Hash: %s (new)%s\n", colors.Yellow, getSrcHashDisplay(currentHash), colors.Reset)
     } else if currentHash != storedHash {
-        fmt.Printf("%s  Hash changed: %s -> %s%s\n", colors.Yellow, getSrcHashDisplay(storedHash), getSrcHashDisplay(currentHash), colors.Reset)
+        fmt.Fprintf(console, "%s  Hash changed: %s -> %s%s\n", colors.Yellow, getSrcHashDisplay(storedHash), getSrcHashDisplay(currentHash), colors.Reset)
     } else {
-        fmt.Printf("%s  Hash: %s (unchanged)%s\n", colors.Green, getSrcHashDisplay(currentHash), colors.Reset)
+        fmt.Fprintf(console, "%s  Hash: %s (unchanged)%s\n", colors.Green, getSrcHashDisplay(currentHash), colors.Reset)
     }
 }
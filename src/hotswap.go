@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// classState is what the hot-swapper remembers about a compiled class
+type classState struct {
+	Hash   string
+	Schema string
+}
+
+// Hotswapper redefines changed classes in a running JVM over JDWP
+type Hotswapper struct {
+	Address    string
+	ClassesDir string
+	snapshot   map[string]classState
+}
+
+// newHotswapper creates a hot-swapper for the JVM debug agent address
+func newHotswapper(debug DebugOptions) *Hotswapper {
+	return &Hotswapper{
+		Address:    debug.Address(),
+		ClassesDir: filepath.Join(currentDir, "target", "classes"),
+		snapshot:   make(map[string]classState),
+	}
+}
+
+// scanClasses hashes every .class file in the classes directory
+func (h *Hotswapper) scanClasses() map[string]string {
+	hashes := make(map[string]string)
+
+	filepath.WalkDir(h.ClassesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".class") {
+			return nil
+		}
+
+		if hash, err := calculateFileHash(path); err == nil {
+			hashes[path] = hash
+		}
+
+		return nil
+	})
+
+	return hashes
+}
+
+// readClassState reads the hash and schema of a class file
+func readClassState(path, hash string) (classState, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return classState{}, nil, err
+	}
+
+	info, err := parseClassFile(data)
+	if err != nil {
+		return classState{}, nil, err
+	}
+
+	return classState{Hash: hash, Schema: info.Schema()}, data, nil
+}
+
+// Snapshot records the classes the running JVM was started with
+func (h *Hotswapper) Snapshot() {
+	h.snapshot = make(map[string]classState)
+
+	for path, hash := range h.scanClasses() {
+
+		if state, _, err := readClassState(path, hash); err == nil {
+			h.snapshot[path] = state
+		}
+	}
+}
+
+// Swap redefines the classes changed since the last snapshot
+// Returns an error when the JVM needs a full restart instead
+func (h *Hotswapper) Swap() (int, error) {
+	current := h.scanClasses()
+
+	// Removed classes can't be unloaded
+	for path := range h.snapshot {
+
+		if _, ok := current[path]; !ok {
+			return 0, fmt.Errorf("class removed: %s", h.className(path))
+		}
+	}
+
+	changed := make(map[string][]byte)
+	states := make(map[string]classState)
+	var schemaChanges []string
+
+	for path, hash := range current {
+		previous, known := h.snapshot[path]
+
+		// New classes are loaded by the JVM when first used
+		if !known || previous.Hash == hash {
+			continue
+		}
+
+		state, data, err := readClassState(path, hash)
+		if err != nil {
+			return 0, err
+		}
+
+		if state.Schema != previous.Schema {
+			schemaChanges = append(schemaChanges, h.className(path))
+		}
+
+		changed[path] = data
+		states[path] = state
+	}
+
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	client, err := dialJDWP(h.Address, 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	// Fields or methods can only change on VMs with unrestricted redefinition
+	if len(schemaChanges) > 0 && !client.canRedefineUnrestricted() {
+		return 0, fmt.Errorf("schema changed: %s", strings.Join(schemaChanges, ", "))
+	}
+
+	classes := make(map[uint64][]byte)
+
+	for path, data := range changed {
+		signature := "L" + strings.ReplaceAll(h.className(path), ".", "/") + ";"
+
+		ids, err := client.classesBySignature(signature)
+		if err != nil {
+			return 0, err
+		}
+
+		// Classes that were never loaded pick up the new bytecode on their own
+		for _, id := range ids {
+			classes[id] = data
+		}
+	}
+
+	if len(classes) > 0 {
+		if err := client.redefineClasses(classes); err != nil {
+			return 0, err
+		}
+	}
+
+	// Remember the new state, including newly added classes
+	for path, hash := range current {
+
+		if state, ok := states[path]; ok {
+			h.snapshot[path] = state
+		} else if _, known := h.snapshot[path]; !known {
+			if state, _, err := readClassState(path, hash); err == nil {
+				h.snapshot[path] = state
+			}
+		}
+	}
+
+	return len(changed), nil
+}
+
+// className returns the binary class name of a .class file
+func (h *Hotswapper) className(path string) string {
+	relPath, err := filepath.Rel(h.ClassesDir, path)
+	if err != nil {
+		relPath = path
+	}
+
+	return strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(relPath), ".class"), "/", ".")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultWatchIgnore lists files that never trigger a rebuild
+// Editor swap and backup files, IDE folders, OS metadata and build output
+var defaultWatchIgnore = []string{
+	"*.swp", "*.swo", "*.swx", "*~", ".#*", "#*#", "4913",
+	"*___jb_tmp___", "*___jb_old___", "*.tmp",
+	".DS_Store", "Thumbs.db", "desktop.ini",
+	".idea/", ".vscode/", ".settings/", ".classpath", ".project", ".factorypath",
+	".git/", ".marn/", "/target/", "/bin/", "/out/", "/build/",
+}
+
+// globPattern is a single compiled line of a .gitignore style pattern list
+type globPattern struct {
+	Source  string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// PathMatcher matches paths against .gitignore style patterns
+type PathMatcher struct {
+	patterns []globPattern
+}
+
+// newPathMatcher compiles .gitignore style patterns
+func newPathMatcher(lines []string) *PathMatcher {
+	m := &PathMatcher{}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := globPattern{Source: line}
+
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+
+		// A leading backslash escapes ! and #
+		line = strings.TrimPrefix(line, "\\")
+
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// Patterns containing a slash are relative to the root, others match at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+
+		p.re = re
+		m.patterns = append(m.patterns, p)
+	}
+
+	return m
+}
+
+// globToRegexp converts a glob with *, ?, [...] and ** to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+
+				// "**/" matches zero or more directories
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+
+		case '?':
+			b.WriteString("[^/]")
+
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteString("[" + class + "]")
+			i += end
+
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
+
+// Match checks a slash-separated path relative to the root
+// The last matching pattern wins, and a match on a parent directory covers everything inside it
+// Returns the pattern that decided the result
+func (m *PathMatcher) Match(relPath string, isDir bool) (bool, string) {
+	relPath = filepath.ToSlash(relPath)
+	parts := strings.Split(relPath, "/")
+
+	// Parent directories first, an excluded directory excludes its content
+	for i := 1; i < len(parts); i++ {
+
+		if matched, source := m.matchOne(strings.Join(parts[:i], "/"), true); matched {
+			return true, source
+		}
+	}
+
+	return m.matchOne(relPath, isDir)
+}
+
+// matchOne applies the patterns to a single path
+func (m *PathMatcher) matchOne(path string, isDir bool) (bool, string) {
+	matched := false
+	source := ""
+
+	for _, p := range m.patterns {
+
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		if p.re.MatchString(path) {
+			matched = !p.negate
+			source = p.Source
+		}
+	}
+
+	return matched, source
+}
+
+// Empty checks if the matcher has no patterns
+func (m *PathMatcher) Empty() bool {
+	return len(m.patterns) == 0
+}
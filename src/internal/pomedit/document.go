@@ -0,0 +1,283 @@
+// Package pomedit edits pom.xml files without reformatting them
+// The document is kept as a stream of raw tokens, so everything that is not edited,
+// including comments, CDATA, attribute order and whitespace, is written back byte for byte
+package pomedit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kind is the kind of a token
+type Kind int
+
+// Token kinds
+const (
+	StartTag Kind = iota
+	EndTag
+	EmptyTag
+	Text
+	CData
+	Comment
+	ProcInst
+	Directive
+)
+
+// Token is a piece of the document with its exact source text
+type Token struct {
+	Kind Kind
+	Name string
+	Raw  string
+}
+
+// Document is an XML document that can be edited in place
+type Document struct {
+	tokens  []*Token
+	newline string
+	indent  string
+}
+
+// Parse reads a document
+func Parse(data []byte) (*Document, error) {
+	doc := &Document{
+		newline: "\n",
+		indent:  detectIndent(data),
+	}
+
+	if bytes.Contains(data, []byte("\r\n")) {
+		doc.newline = "\r\n"
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	for {
+		start := decoder.InputOffset()
+
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		raw := string(data[start:decoder.InputOffset()])
+
+		switch t := token.(type) {
+		case xml.StartElement:
+
+			if strings.HasSuffix(raw, "/>") {
+				doc.tokens = append(doc.tokens, &Token{Kind: EmptyTag, Name: t.Name.Local, Raw: raw})
+			} else {
+				doc.tokens = append(doc.tokens, &Token{Kind: StartTag, Name: t.Name.Local, Raw: raw})
+			}
+
+		case xml.EndElement:
+
+			// The decoder reports an empty end tag for <name/>
+			if raw != "" {
+				doc.tokens = append(doc.tokens, &Token{Kind: EndTag, Name: t.Name.Local, Raw: raw})
+			}
+
+		case xml.CharData:
+
+			if strings.HasPrefix(raw, "<![CDATA[") {
+				doc.tokens = append(doc.tokens, &Token{Kind: CData, Raw: raw})
+			} else {
+				doc.tokens = append(doc.tokens, &Token{Kind: Text, Raw: raw})
+			}
+
+		case xml.Comment:
+			doc.tokens = append(doc.tokens, &Token{Kind: Comment, Raw: raw})
+
+		case xml.ProcInst:
+			doc.tokens = append(doc.tokens, &Token{Kind: ProcInst, Raw: raw})
+
+		case xml.Directive:
+			doc.tokens = append(doc.tokens, &Token{Kind: Directive, Raw: raw})
+		}
+	}
+
+	if err := doc.check(); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// check makes sure the start and end tags match
+func (d *Document) check() error {
+	var open []string
+
+	for _, token := range d.tokens {
+
+		switch token.Kind {
+		case StartTag:
+			open = append(open, token.Name)
+
+		case EndTag:
+
+			if len(open) == 0 || open[len(open)-1] != token.Name {
+				return fmt.Errorf("unexpected </%s>", token.Name)
+			}
+
+			open = open[:len(open)-1]
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("<%s> is not closed", open[len(open)-1])
+	}
+
+	return nil
+}
+
+// Bytes returns the document
+// An unedited document is returned exactly as it was parsed
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+
+	for _, token := range d.tokens {
+		buf.WriteString(token.Raw)
+	}
+
+	return buf.Bytes()
+}
+
+// Root returns the root element
+func (d *Document) Root() *Element {
+	for _, token := range d.tokens {
+
+		if token.Kind == StartTag || token.Kind == EmptyTag {
+			return &Element{doc: d, start: token}
+		}
+	}
+
+	return nil
+}
+
+// Find returns the elements at a path like project/dependencies/dependency
+func (d *Document) Find(path string) []*Element {
+	parts := strings.Split(path, "/")
+
+	root := d.Root()
+	if root == nil || root.Name() != parts[0] {
+		return nil
+	}
+
+	elements := []*Element{root}
+
+	for _, name := range parts[1:] {
+		var children []*Element
+
+		for _, element := range elements {
+			children = append(children, element.ChildrenNamed(name)...)
+		}
+
+		elements = children
+	}
+
+	return elements
+}
+
+// First returns the first element at a path, or nil
+func (d *Document) First(path string) *Element {
+	if elements := d.Find(path); len(elements) > 0 {
+		return elements[0]
+	}
+
+	return nil
+}
+
+// Ensure returns the first element at a path, creating the missing elements
+func (d *Document) Ensure(path string) (*Element, error) {
+	parts := strings.Split(path, "/")
+
+	element := d.Root()
+	if element == nil || element.Name() != parts[0] {
+		return nil, fmt.Errorf("no <%s> element", parts[0])
+	}
+
+	for _, name := range parts[1:] {
+		child := element.Child(name)
+		if child == nil {
+			child = element.AppendChild(name)
+		}
+
+		element = child
+	}
+
+	return element, nil
+}
+
+// Set sets the text of the element at a path, creating it when it is missing
+func (d *Document) Set(path string, text string) error {
+	element, err := d.Ensure(path)
+	if err != nil {
+		return err
+	}
+
+	element.SetText(text)
+	return nil
+}
+
+// index returns the position of a token
+func (d *Document) index(token *Token) int {
+	for i, t := range d.tokens {
+
+		if t == token {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// splice replaces tokens[start:end] with new tokens
+func (d *Document) splice(start, end int, tokens ...*Token) {
+	rest := append([]*Token{}, d.tokens[end:]...)
+	d.tokens = append(append(d.tokens[:start], tokens...), rest...)
+}
+
+// lineIndent returns the indentation of the line a token starts on
+// Returns false when other content comes before the token on its line
+func (d *Document) lineIndent(i int) (string, bool) {
+	if i == 0 || d.tokens[i-1].Kind != Text {
+		return "", i == 0
+	}
+
+	text := d.tokens[i-1].Raw
+	newline := strings.LastIndex(text, "\n")
+
+	if newline < 0 {
+		return "", false
+	}
+
+	indent := text[newline+1:]
+	return indent, strings.TrimLeft(indent, " \t") == ""
+}
+
+// detectIndent returns the indentation of one level, taken from the first indented line
+func detectIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+
+		if trimmed != line && strings.HasPrefix(trimmed, "<") {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+
+	return "    "
+}
+
+// escapeText escapes text for use in an element
+func escapeText(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
@@ -0,0 +1,277 @@
+package pomedit
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Element is an element of a document
+// It stays valid while the document is edited around it
+type Element struct {
+	doc   *Document
+	start *Token
+}
+
+// Name returns the local name of the element
+func (e *Element) Name() string {
+	return e.start.Name
+}
+
+// bounds returns the positions of the start and the end tag
+// Both are the same for an empty tag like <name/>
+func (e *Element) bounds() (int, int) {
+	start := e.doc.index(e.start)
+	if start < 0 || e.start.Kind == EmptyTag {
+		return start, start
+	}
+
+	depth := 0
+
+	for i := start; i < len(e.doc.tokens); i++ {
+
+		switch e.doc.tokens[i].Kind {
+		case StartTag:
+			depth++
+
+		case EndTag:
+			depth--
+
+			if depth == 0 {
+				return start, i
+			}
+		}
+	}
+
+	return start, len(e.doc.tokens) - 1
+}
+
+// Children returns the child elements
+func (e *Element) Children() []*Element {
+	start, end := e.bounds()
+
+	var children []*Element
+	depth := 0
+
+	for i := start + 1; i < end; i++ {
+		token := e.doc.tokens[i]
+
+		switch token.Kind {
+		case StartTag:
+
+			if depth == 0 {
+				children = append(children, &Element{doc: e.doc, start: token})
+			}
+
+			depth++
+
+		case EndTag:
+			depth--
+
+		case EmptyTag:
+
+			if depth == 0 {
+				children = append(children, &Element{doc: e.doc, start: token})
+			}
+		}
+	}
+
+	return children
+}
+
+// ChildrenNamed returns the child elements with a name
+func (e *Element) ChildrenNamed(name string) []*Element {
+	var children []*Element
+
+	for _, child := range e.Children() {
+
+		if child.Name() == name {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// Child returns the first element at a path below this element, or nil
+func (e *Element) Child(path string) *Element {
+	element := e
+
+	for _, name := range strings.Split(path, "/") {
+		children := element.ChildrenNamed(name)
+		if len(children) == 0 {
+			return nil
+		}
+
+		element = children[0]
+	}
+
+	return element
+}
+
+// Text returns the trimmed text of the element, including CDATA sections
+func (e *Element) Text() string {
+	start, end := e.bounds()
+
+	var text strings.Builder
+	depth := 0
+
+	for i := start + 1; i < end; i++ {
+		token := e.doc.tokens[i]
+
+		switch token.Kind {
+		case StartTag:
+			depth++
+
+		case EndTag:
+			depth--
+
+		case Text, CData:
+
+			if depth == 0 {
+				text.WriteString(unescapeText(token))
+			}
+		}
+	}
+
+	return strings.TrimSpace(text.String())
+}
+
+// SetText replaces the content of the element with text
+// An element holding a single CDATA section keeps it
+func (e *Element) SetText(text string) {
+	start, end := e.bounds()
+
+	raw := escapeText(text)
+
+	if end-start == 2 && e.doc.tokens[start+1].Kind == CData && !strings.Contains(text, "]]>") {
+		raw = "<![CDATA[" + text + "]]>"
+	}
+
+	if e.start.Kind == EmptyTag {
+		e.start.Kind = StartTag
+		e.start.Raw = strings.TrimRight(strings.TrimSuffix(e.start.Raw, "/>"), " \t\r\n") + ">"
+		e.doc.splice(start+1, start+1, &Token{Kind: Text, Raw: raw}, &Token{Kind: EndTag, Name: e.Name(), Raw: "</" + e.rawName() + ">"})
+		return
+	}
+
+	e.doc.splice(start+1, end, &Token{Kind: Text, Raw: raw})
+}
+
+// AppendChild adds an empty element as the last child and returns it
+// It is indented like the other children, or one level deeper than this element
+func (e *Element) AppendChild(name string) *Element {
+	start, end := e.bounds()
+	doc := e.doc
+
+	parentIndent, _ := doc.lineIndent(start)
+	childIndent := parentIndent + doc.indent
+
+	child := &Token{Kind: StartTag, Name: name, Raw: "<" + name + ">"}
+	tokens := []*Token{{Kind: Text, Raw: doc.newline + childIndent}, child, {Kind: EndTag, Name: name, Raw: "</" + name + ">"}}
+
+	// An empty tag is opened up first
+	if e.start.Kind == EmptyTag {
+		e.start.Kind = StartTag
+		e.start.Raw = strings.TrimRight(strings.TrimSuffix(e.start.Raw, "/>"), " \t\r\n") + ">"
+
+		tokens = append(tokens, &Token{Kind: Text, Raw: doc.newline + parentIndent}, &Token{Kind: EndTag, Name: e.Name(), Raw: "</" + e.rawName() + ">"})
+		doc.splice(start+1, start+1, tokens...)
+
+		return &Element{doc: doc, start: child}
+	}
+
+	children := e.Children()
+
+	if len(children) > 0 {
+		last := children[len(children)-1]
+		_, lastEnd := last.bounds()
+
+		// Siblings keep the indentation they already have
+		if indent, ok := doc.lineIndent(doc.index(last.start)); ok {
+			tokens[0].Raw = doc.newline + indent
+		}
+
+		doc.splice(lastEnd+1, lastEnd+1, tokens...)
+		return &Element{doc: doc, start: child}
+	}
+
+	// Only whitespace inside, the child goes on its own line between the tags
+	blank := true
+
+	for _, token := range doc.tokens[start+1 : end] {
+
+		if token.Kind != Text || strings.TrimSpace(token.Raw) != "" {
+			blank = false
+		}
+	}
+
+	if blank {
+		tokens = append(tokens, &Token{Kind: Text, Raw: doc.newline + parentIndent})
+		doc.splice(start+1, end, tokens...)
+		return &Element{doc: doc, start: child}
+	}
+
+	// Text or comments inside, the child goes after them
+	if previous := doc.tokens[end-1]; previous.Kind != Text || !strings.Contains(previous.Raw, "\n") || strings.TrimSpace(previous.Raw) != "" {
+		tokens = append(tokens, &Token{Kind: Text, Raw: doc.newline + parentIndent})
+		doc.splice(end, end, tokens...)
+	} else {
+		doc.splice(end-1, end-1, tokens...)
+	}
+
+	return &Element{doc: doc, start: child}
+}
+
+// AppendText adds an element with text as the last child and returns it
+func (e *Element) AppendText(name string, text string) *Element {
+	child := e.AppendChild(name)
+	child.SetText(text)
+	return child
+}
+
+// Remove removes the element together with the indentation of its line
+func (e *Element) Remove() {
+	start, end := e.bounds()
+	doc := e.doc
+
+	if indent, ok := doc.lineIndent(start); ok && start > 0 {
+		previous := doc.tokens[start-1]
+
+		// Cut the line break and indentation in front of the element
+		cut := len(previous.Raw) - len(indent)
+		cut = strings.LastIndex(previous.Raw[:cut], "\n")
+
+		if cut > 0 && previous.Raw[cut-1] == '\r' {
+			cut--
+		}
+
+		previous.Raw = previous.Raw[:cut]
+	}
+
+	doc.splice(start, end+1)
+}
+
+// rawName returns the name of the element as written, including a namespace prefix
+func (e *Element) rawName() string {
+	name := strings.TrimPrefix(e.start.Raw, "<")
+
+	if i := strings.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// unescapeText returns the text of a text or CDATA token
+func unescapeText(token *Token) string {
+	if token.Kind == CData {
+		return strings.TrimSuffix(strings.TrimPrefix(token.Raw, "<![CDATA["), "]]>")
+	}
+
+	var text string
+	if err := xml.Unmarshal([]byte("<t>"+token.Raw+"</t>"), &text); err != nil {
+		return token.Raw
+	}
+
+	return text
+}
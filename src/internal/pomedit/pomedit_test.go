@@ -0,0 +1,175 @@
+package pomedit
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update rewrites the expected files, run with: go test ./internal/pomedit -update
+var update = flag.Bool("update", false, "update the expected files in testdata")
+
+// goldenTests are edits applied to testdata/<name>.input.xml, compared with testdata/<name>.expected.xml
+var goldenTests = []struct {
+	name string
+	edit func(t *testing.T, doc *Document)
+}{
+	{
+		// Nothing is edited, the document must come back byte for byte
+		name: "roundtrip",
+		edit: func(t *testing.T, doc *Document) {},
+	},
+	{
+		name: "append",
+		edit: func(t *testing.T, doc *Document) {
+			dependencies := doc.First("project/dependencies")
+			if dependencies == nil {
+				t.Fatal("no dependencies element")
+			}
+
+			dependency := dependencies.AppendChild("dependency")
+			dependency.AppendText("groupId", "org.junit.jupiter")
+			dependency.AppendText("artifactId", "junit-jupiter")
+			dependency.AppendText("scope", "test")
+
+			// An empty tag is opened up
+			plugin := doc.First("project/build/plugins").AppendChild("plugin")
+			plugin.AppendText("artifactId", "maven-compiler-plugin")
+		},
+	},
+	{
+		name: "ensure",
+		edit: func(t *testing.T, doc *Document) {
+			if err := doc.Set("project/properties/guava.version", "33.0.0-jre"); err != nil {
+				t.Fatal(err)
+			}
+
+			dependencies, err := doc.Ensure("project/dependencyManagement/dependencies")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dependency := dependencies.AppendChild("dependency")
+			dependency.AppendText("groupId", "com.google.guava")
+			dependency.AppendText("artifactId", "guava")
+			dependency.AppendText("version", "${guava.version}")
+
+			// Existing elements are reused
+			again, err := doc.Ensure("project/dependencyManagement/dependencies")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(again.Children()) != 1 {
+				t.Fatalf("Ensure created a second element")
+			}
+		},
+	},
+	{
+		name: "settext",
+		edit: func(t *testing.T, doc *Document) {
+			doc.First("project/properties/java.version").SetText("21")
+
+			// A CDATA section stays CDATA
+			doc.First("project/properties/note").SetText("new <text>")
+
+			// Empty tags are opened up and text is escaped
+			doc.First("project/properties/empty").SetText("a & b")
+			doc.First("project/properties/spaced").SetText("x")
+
+			// New lines follow the CRLF line endings and tab indentation
+			doc.First("project/properties").AppendText("added", "1")
+		},
+	},
+	{
+		name: "remove",
+		edit: func(t *testing.T, doc *Document) {
+			for _, dependency := range doc.Find("project/dependencies/dependency") {
+				artifactID := dependency.Child("artifactId").Text()
+
+				if artifactID == "slf4j-api" || artifactID == "inline" {
+					dependency.Remove()
+				}
+			}
+		},
+	},
+}
+
+func TestGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", test.name+".input.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.edit(t, doc)
+			got := doc.Bytes()
+
+			expectedPath := filepath.Join("testdata", test.name+".expected.xml")
+
+			if *update {
+
+				if err := os.WriteFile(expectedPath, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			expected, err := os.ReadFile(expectedPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, expected) {
+				t.Errorf("%s differs from %s\ngot:\n%s\nexpected:\n%s", test.name, expectedPath, got, expected)
+			}
+
+			// The result must parse again
+			if _, err := Parse(got); err != nil {
+				t.Errorf("edited document does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	doc, err := Parse([]byte("<p><a>  x &amp; y </a><b><![CDATA[<raw>]]></b><c/></p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"p/a": "x & y",
+		"p/b": "<raw>",
+		"p/c": "",
+	}
+
+	for path, expected := range tests {
+
+		if got := doc.First(path).Text(); got != expected {
+			t.Errorf("%s: got %q, expected %q", path, got, expected)
+		}
+	}
+
+	if doc.First("p/missing") != nil {
+		t.Errorf("missing element was found")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"<a><b></a>", "<a>", "</a>"} {
+
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("%q parsed without an error", input)
+		}
+	}
+}
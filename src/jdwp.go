@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// JDWP command sets and commands used by marn
+const (
+	jdwpVirtualMachine    = 1
+	jdwpClassesBySig      = 2
+	jdwpDispose           = 6
+	jdwpIDSizes           = 7
+	jdwpRedefineClasses   = 18
+	jdwpCapabilitiesNew   = 17
+	jdwpReplyFlag         = 0x80
+	jdwpHandshake         = "JDWP-Handshake"
+	jdwpCanUnrestrictedly = 9 // index of canUnrestrictedlyRedefineClasses in CapabilitiesNew
+	jdwpTimeout           = 30 * time.Second
+)
+
+// jdwpErrors maps the JDWP error codes returned by RedefineClasses to messages
+var jdwpErrors = map[int]string{
+	21: "invalid class",
+	60: "invalid class format",
+	61: "circular class definition",
+	62: "class fails verification",
+	63: "adding methods is not supported",
+	64: "schema change is not supported",
+	65: "invalid type state",
+	66: "hierarchy change is not supported",
+	67: "deleting methods is not supported",
+	68: "unsupported class file version",
+	69: "class names don't match",
+	70: "class modifiers change is not supported",
+	71: "method modifiers change is not supported",
+	72: "class attribute change is not supported",
+	99: "not implemented",
+}
+
+// JDWPError is an error code returned by the target VM
+type JDWPError struct {
+	Code int
+}
+
+// Error returns the message for the JDWP error code
+func (e *JDWPError) Error() string {
+	if msg, ok := jdwpErrors[e.Code]; ok {
+		return fmt.Sprintf("%s (JDWP error %d)", msg, e.Code)
+	}
+
+	return fmt.Sprintf("JDWP error %d", e.Code)
+}
+
+// JDWPClient is a minimal Java Debug Wire Protocol client
+type JDWPClient struct {
+	conn          net.Conn
+	nextID        uint32
+	refTypeIDSize int
+}
+
+// dialJDWP connects to a JDWP agent and performs the handshake
+func dialJDWP(address string, timeout time.Duration) (*JDWPClient, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write([]byte(jdwpHandshake)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply := make([]byte, len(jdwpHandshake))
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != jdwpHandshake {
+		conn.Close()
+		return nil, fmt.Errorf("JDWP handshake failed, is another debugger attached?")
+	}
+
+	client := &JDWPClient{conn: conn}
+
+	// Reference type IDs have a VM specific size
+	sizes, err := client.command(jdwpVirtualMachine, jdwpIDSizes, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if len(sizes) < 20 {
+		conn.Close()
+		return nil, fmt.Errorf("invalid IDSizes reply")
+	}
+
+	client.refTypeIDSize = int(binary.BigEndian.Uint32(sizes[12:16]))
+	return client, nil
+}
+
+// command sends a command packet and waits for its reply
+// Event packets sent by the VM in the meantime are ignored
+func (c *JDWPClient) command(commandSet, command byte, data []byte) ([]byte, error) {
+	c.nextID++
+	id := c.nextID
+
+	c.conn.SetDeadline(time.Now().Add(jdwpTimeout))
+
+	header := make([]byte, 11)
+	binary.BigEndian.PutUint32(header[0:4], uint32(11+len(data)))
+	binary.BigEndian.PutUint32(header[4:8], id)
+	header[9] = commandSet
+	header[10] = command
+
+	if _, err := c.conn.Write(append(header, data...)); err != nil {
+		return nil, err
+	}
+
+	for {
+		reply := make([]byte, 11)
+		if _, err := io.ReadFull(c.conn, reply); err != nil {
+			return nil, err
+		}
+
+		length := binary.BigEndian.Uint32(reply[0:4])
+		if length < 11 {
+			return nil, fmt.Errorf("invalid JDWP packet length %d", length)
+		}
+
+		body := make([]byte, length-11)
+		if _, err := io.ReadFull(c.conn, body); err != nil {
+			return nil, err
+		}
+
+		if reply[8] != jdwpReplyFlag || binary.BigEndian.Uint32(reply[4:8]) != id {
+			continue
+		}
+
+		if code := int(binary.BigEndian.Uint16(reply[9:11])); code != 0 {
+			return nil, &JDWPError{Code: code}
+		}
+
+		return body, nil
+	}
+}
+
+// canRedefineUnrestricted checks if the VM accepts schema changes (e.g. DCEVM)
+func (c *JDWPClient) canRedefineUnrestricted() bool {
+	capabilities, err := c.command(jdwpVirtualMachine, jdwpCapabilitiesNew, nil)
+	if err != nil || len(capabilities) <= jdwpCanUnrestrictedly {
+		return false
+	}
+
+	return capabilities[jdwpCanUnrestrictedly] != 0
+}
+
+// classesBySignature returns the reference type IDs of the loaded classes with a signature
+func (c *JDWPClient) classesBySignature(signature string) ([]uint64, error) {
+	data := make([]byte, 4, 4+len(signature))
+	binary.BigEndian.PutUint32(data, uint32(len(signature)))
+	data = append(data, signature...)
+
+	reply, err := c.command(jdwpVirtualMachine, jdwpClassesBySig, data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(reply)
+
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, count)
+	entry := make([]byte, 1+c.refTypeIDSize+4)
+
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(r, entry); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, c.readID(entry[1:1+c.refTypeIDSize]))
+	}
+
+	return ids, nil
+}
+
+// redefineClasses replaces the bytecode of loaded classes
+func (c *JDWPClient) redefineClasses(classes map[uint64][]byte) error {
+	var data bytes.Buffer
+
+	binary.Write(&data, binary.BigEndian, uint32(len(classes)))
+
+	for id, classBytes := range classes {
+		data.Write(c.writeID(id))
+		binary.Write(&data, binary.BigEndian, uint32(len(classBytes)))
+		data.Write(classBytes)
+	}
+
+	_, err := c.command(jdwpVirtualMachine, jdwpRedefineClasses, data.Bytes())
+	return err
+}
+
+// Close disposes the debugger connection and lets the VM continue
+func (c *JDWPClient) Close() {
+	c.command(jdwpVirtualMachine, jdwpDispose, nil)
+	c.conn.Close()
+}
+
+// readID decodes a reference type ID of the VM specific size
+func (c *JDWPClient) readID(b []byte) uint64 {
+	var id uint64
+
+	for _, v := range b {
+		id = id<<8 | uint64(v)
+	}
+
+	return id
+}
+
+// writeID encodes a reference type ID of the VM specific size
+func (c *JDWPClient) writeID(id uint64) []byte {
+	b := make([]byte, c.refTypeIDSize)
+
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(id)
+		id >>= 8
+	}
+
+	return b
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// keyPollInterval is how often the key reader checks if it was stopped
+const keyPollInterval = 100 * time.Millisecond
+
+// startKeyReader reads single key presses from stdin
+// Returns a nil channel when stdin is not a terminal
+// stop ends the reader and puts the terminal back, so commands started afterwards get every key
+func startKeyReader() (<-chan byte, func()) {
+	if !isTerminal(os.Stdin) {
+		return nil, func() {}
+	}
+
+	restore, err := enableKeyInput(os.Stdin)
+	if err != nil {
+		return nil, func() {}
+	}
+
+	keys := make(chan byte, 8)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		buf := make([]byte, 16)
+
+		for {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+
+			// Only read when a key is waiting, a blocked read could not be stopped
+			ready, err := waitForInput(os.Stdin, keyPollInterval)
+			if err != nil {
+				return
+			}
+
+			if !ready {
+				continue
+			}
+
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for _, key := range buf[:n] {
+				select {
+				case keys <- key:
+				case <-quit:
+					return
+				}
+			}
+		}
+	}()
+
+	stop := func() {
+		close(quit)
+		<-done
+		restore()
+	}
+
+	return keys, stop
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListOptions holds the options passed to 'marn list'
+type ListOptions struct {
+	Depth   int // 0 shows the whole tree
+	Pattern string
+	JSON    bool
+	Refresh bool
+}
+
+// parseListArgs parses the arguments given to 'marn list'
+func parseListArgs(args []string) (ListOptions, error) {
+	var opts ListOptions
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--json":
+			opts.JSON = true
+
+		case arg == "--refresh":
+			opts.Refresh = true
+
+		case (arg == "--depth" || arg == "--pattern") && i+1 >= len(args):
+			return opts, fmt.Errorf("option '%s' needs a value", arg)
+
+		case arg == "--depth" || strings.HasPrefix(arg, "--depth="):
+			value := strings.TrimPrefix(arg, "--depth=")
+			if arg == "--depth" {
+				value = args[i+1]
+				i++
+			}
+
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 1 {
+				return opts, fmt.Errorf("invalid depth '%s', use a number from 1", value)
+			}
+
+			opts.Depth = depth
+
+		case arg == "--pattern" || strings.HasPrefix(arg, "--pattern="):
+			opts.Pattern = strings.TrimPrefix(arg, "--pattern=")
+			if arg == "--pattern" {
+				opts.Pattern = args[i+1]
+				i++
+			}
+
+			if _, err := path.Match(opts.Pattern, ""); err != nil {
+				return opts, fmt.Errorf("invalid pattern '%s'", opts.Pattern)
+			}
+
+		default:
+			return opts, fmt.Errorf("unknown option '%s'", arg)
+		}
+	}
+
+	return opts, nil
+}
+
+// listDependencies implements 'marn list', printing the resolved dependency tree
+func listDependencies() {
+	opts, err := parseListArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	root, err := loadDependencyTree(opts.Refresh)
+	if err != nil {
+		fmt.Printf("%sError: Could not resolve the dependency tree: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	annotateDependencyTree(root)
+
+	tree := filterDependencyTree(root, opts, 0)
+
+	// Matches below --depth are cut off with the rest of the tree
+	hidden := 0
+	if opts.Pattern != "" {
+		hidden = countDependencyMatches(root, opts.Pattern)
+		if tree != nil {
+			hidden -= countDependencyMatches(tree, opts.Pattern)
+		}
+	}
+
+	if tree == nil && hidden > 0 {
+		fmt.Printf("%sNo dependency matches '%s' within --depth %d, %d match(es) deeper were hidden%s\n", colors.Yellow, opts.Pattern, opts.Depth, hidden, colors.Reset)
+		os.Exit(1)
+	}
+
+	if tree == nil {
+		fmt.Printf("%sNo dependency matches '%s'%s\n", colors.Yellow, opts.Pattern, colors.Reset)
+		os.Exit(1)
+	}
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(tree)
+		return
+	}
+
+	fmt.Println(tree.String())
+	printDependencyChildren(tree, "")
+	fmt.Println()
+
+	if hidden > 0 {
+		fmt.Printf("%s%d more match(es) of '%s' below --depth %d were hidden%s\n", colors.Yellow, hidden, opts.Pattern, opts.Depth, colors.Reset)
+	}
+
+	printListSummary(root)
+}
+
+// annotateDependencyTree marks conflicting versions and the local siblings marn builds from source
+func annotateDependencyTree(root *DependencyNode) {
+	versions := make(map[string][]string)
+
+	root.Walk(func(node *DependencyNode) {
+		key := node.Key()
+
+		for _, version := range versions[key] {
+
+			if version == node.Version {
+				return
+			}
+		}
+
+		versions[key] = append(versions[key], node.Version)
+	})
+
+	local := getLocalSiblings()
+
+	root.Walk(func(node *DependencyNode) {
+
+		if node == root {
+			return
+		}
+
+		for _, version := range versions[node.Key()] {
+
+			if version != node.Version {
+				node.Conflicts = append(node.Conflicts, version)
+			}
+		}
+
+		if dir, ok := local[node.Key()]; ok {
+			node.Local = dir
+		}
+	})
+}
+
+// getLocalSiblings returns the local dependencies marn builds from source, by group:artifact
+// Paths are relative to the project
+func getLocalSiblings() map[string]string {
+	siblings := make(map[string]string)
+
+	for _, dir := range getLocalDependencies() {
+		key := getPomKey(filepath.Join(dir, "pom.xml"))
+		if key == "" {
+			continue
+		}
+
+		siblings[key] = displayPath(dir)
+	}
+
+	return siblings
+}
+
+// getPomKey returns group:artifact of a pom.xml file, the group may come from the parent
+func getPomKey(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var pom POM
+	if err := xml.Unmarshal(content, &pom); err != nil || pom.ArtifactID == "" {
+		return ""
+	}
+
+	groupID := pom.GroupID
+	if groupID == "" {
+		groupID = pom.Parent.GroupID
+	}
+
+	return groupID + ":" + pom.ArtifactID
+}
+
+// filterDependencyTree returns a copy of the tree cut at the depth
+// With a pattern only matching artifacts and the nodes leading to them are kept
+func filterDependencyTree(node *DependencyNode, opts ListOptions, depth int) *DependencyNode {
+	filtered := *node
+	filtered.Children = nil
+
+	if opts.Depth == 0 || depth < opts.Depth {
+
+		for _, child := range node.Children {
+
+			if c := filterDependencyTree(child, opts, depth+1); c != nil {
+				c.Parent = &filtered
+				filtered.Children = append(filtered.Children, c)
+			}
+		}
+	}
+
+	// The root stays, unless nothing below it matches
+	if depth == 0 && opts.Pattern != "" && len(filtered.Children) == 0 {
+		return nil
+	}
+
+	if depth == 0 || opts.Pattern == "" || len(filtered.Children) > 0 || matchesDependencyPattern(node, opts.Pattern) {
+		return &filtered
+	}
+
+	return nil
+}
+
+// countDependencyMatches counts the artifacts below a node that match a pattern
+func countDependencyMatches(node *DependencyNode, pattern string) int {
+	count := 0
+
+	for _, child := range node.Children {
+
+		if matchesDependencyPattern(child, pattern) {
+			count++
+		}
+
+		count += countDependencyMatches(child, pattern)
+	}
+
+	return count
+}
+
+// matchesDependencyPattern reports whether a glob matches the artifact ID, group:artifact or group:artifact:version
+func matchesDependencyPattern(node *DependencyNode, pattern string) bool {
+	for _, name := range []string{node.ArtifactID, node.Key(), node.String()} {
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printDependencyChildren prints the children of a node with tree lines
+func printDependencyChildren(node *DependencyNode, prefix string) {
+	for i, child := range node.Children {
+		branch, indent := "├─ ", "│  "
+		if i == len(node.Children)-1 {
+			branch, indent = "└─ ", "   "
+		}
+
+		fmt.Println(prefix + branch + formatDependencyNode(child))
+		printDependencyChildren(child, prefix+indent)
+	}
+}
+
+// formatDependencyNode formats a node of the tree with colors
+// Conflicts are red, SNAPSHOTs yellow and local siblings blue
+func formatDependencyNode(node *DependencyNode) string {
+	text := node.String()
+
+	switch {
+	case len(node.Conflicts) > 0:
+		text = colors.Red + text + colors.Reset
+
+	case strings.HasSuffix(node.Version, "SNAPSHOT"):
+		text = colors.Yellow + text + colors.Reset
+	}
+
+	if node.Scope != "" && node.Scope != "compile" {
+		text += " (" + node.Scope + ")"
+	}
+
+	if node.Optional {
+		text += " optional"
+	}
+
+	if node.Local != "" {
+		text += " " + colors.Blue + "local " + node.Local + colors.Reset
+	}
+
+	if len(node.Conflicts) > 0 && !node.Omitted {
+		text += " " + colors.Red + "conflicts with " + strings.Join(node.Conflicts, ", ") + colors.Reset
+	}
+
+	if node.Note != "" {
+		text += " " + node.Note
+	}
+
+	return text
+}
+
+// printListSummary counts the resolved dependencies and warns about conflicts and missing siblings
+func printListSummary(root *DependencyNode) {
+	resolved := make(map[string]bool)
+	conflicts := make(map[string]bool)
+	var snapshots []*DependencyNode
+
+	root.Walk(func(node *DependencyNode) {
+
+		if node == root || node.Omitted {
+			return
+		}
+
+		if !resolved[node.Key()] && strings.HasSuffix(node.Version, "SNAPSHOT") {
+			snapshots = append(snapshots, node)
+		}
+
+		resolved[node.Key()] = true
+
+		if len(node.Conflicts) > 0 {
+			conflicts[node.Key()] = true
+		}
+	})
+
+	fmt.Printf("%s%d dependencies, %d with conflicting versions, %d SNAPSHOTs%s\n", colors.Blue, len(resolved), len(conflicts), len(snapshots), colors.Reset)
+
+	// SNAPSHOTs without a sibling come from whatever was last installed in ~/.m2
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].String() < snapshots[j].String()
+	})
+
+	for _, node := range snapshots {
+		sibling := filepath.Join(currentDir, "..", node.ArtifactID, "pom.xml")
+
+		// A directory with the same name only counts when it is the same artifact
+		if node.Local == "" && getPomKey(sibling) != node.Key() {
+			fmt.Printf("%sWarning: %s has no sibling project in ../%s, the copy in the local repository may be stale%s\n", colors.Yellow, node, node.ArtifactID, colors.Reset)
+		}
+	}
+}
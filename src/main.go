@@ -52,6 +52,31 @@ func main() {
 
     command := os.Args[1]
 
+    // Commands running Maven can report diagnostics for CI and editors
+    switch command {
+    case "install", "install-deps", "link", "build", "test", "package", "clean":
+        args, err := parseFormatFlag(os.Args)
+        if err != nil {
+            fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+            os.Exit(1)
+        }
+
+        os.Args = args
+    }
+
+    // Maven flags like -P dev or anything after -- are passed on to Maven
+    // 'marn run' leaves them to the application, its Maven flags come from marn.mavenArgs and MAVEN_ARGS
+    switch command {
+    case "install", "install-deps", "link", "build", "test", "package", "clean", "watch", "outdated", "upgrade", "upgrade-interactive", "why", "list":
+        args, err := parseMavenArgs(os.Args)
+        if err != nil {
+            fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+            os.Exit(1)
+        }
+
+        os.Args = args
+    }
+
     switch command {
     case "init":
         initMarn()
@@ -61,6 +86,20 @@ func main() {
         linkProject()
     case "install-deps":
         installDependencies()
+    case "add":
+        addDependencies()
+    case "remove":
+        removeDependencies()
+    case "outdated":
+        showOutdated()
+    case "upgrade":
+        upgradeDependencies()
+    case "upgrade-interactive":
+        upgradeInteractive()
+    case "why":
+        showWhy()
+    case "list":
+        listDependencies()
     case "build":
         buildProject()
     case "test":
@@ -73,6 +112,12 @@ func main() {
         cleanProject()
     case "watch":
         watchMode()
+    case "ps":
+        listProcesses()
+    case "stop":
+        stopProcesses()
+    case "logs":
+        showLogs()
     case "version", "--version", "-v":
         showVersion()
     case "help", "--help", "-h":
@@ -81,6 +126,11 @@ func main() {
         // Check if it's a custom script
         executeScript(command)
     }
+
+    // Commands that failed have already written their --format output
+    if outputFormat != "" {
+        exitCommand(0)
+    }
 }
 
 // showVersion displays the version
@@ -99,16 +149,54 @@ func showHelp() {
     fmt.Println("  init         Install marn globally (copies binary to PATH)")
     fmt.Println("  install      Install dependencies (mvn dependency:resolve)")
     fmt.Println("  link         Link current project to local Maven repository (~/.m2)")
+    fmt.Println("               --clean       Run mvn clean first")
     fmt.Println("  install-deps Install dependencies (mvn dependency:resolve)")
-    fmt.Println("  build        Build the project (mvn clean compile)")
+    fmt.Println("  add <dep>    Add dependencies to pom.xml, like group:artifact@version")
+    fmt.Println("               -D, --dev     Add as test dependencies")
+    fmt.Println("               --scope <s>   Add with a scope")
+    fmt.Println("               --managed     Add to <dependencyManagement>")
+    fmt.Println("  remove <dep> Remove dependencies from pom.xml")
+    fmt.Println("  outdated     List newer versions of dependencies and plugins")
+    fmt.Println("               --json        Print the report as JSON")
+    fmt.Println("  upgrade      Upgrade dependencies and plugins in pom.xml")
+    fmt.Println("               <dep>...      Only upgrade these artifacts, like guava or guava@33.0.0-jre")
+    fmt.Println("               --patch, --minor (default), --latest  How far to upgrade")
+    fmt.Println("  upgrade-interactive")
+    fmt.Println("               Pick upgrades with checkboxes")
+    fmt.Println("  why <dep>    Show the dependency paths that bring in an artifact")
+    fmt.Println("               --refresh     Resolve the dependency tree again")
+    fmt.Println("  list         Show the resolved dependency tree")
+    fmt.Println("               --depth <n>   Only show n levels")
+    fmt.Println("               --pattern <g> Only show artifacts matching a glob, like 'jackson-*'")
+    fmt.Println("               --json        Print the tree as JSON")
+    fmt.Println("  build        Build the project (mvn package)")
+    fmt.Println("               --clean       Run mvn clean first")
     fmt.Println("  test         Run tests (mvn test)")
+    fmt.Println("               --watch       Watch for changes and run the affected tests")
     fmt.Println("  package      Package the project (mvn package)")
     fmt.Println("  run          Build and run the JAR")
+    fmt.Println("               --exec        Run the main class from target/classes")
+    fmt.Println("               --clean       Run mvn clean first")
+    fmt.Println("               --main <cls>  Main class to run (implies --exec)")
+    fmt.Println("               --config <n>  Use the marn.run.<n>.* run configuration")
+    fmt.Println("               --debug[=port][,suspend]  Start with the JDWP debug agent")
     fmt.Println("  clean        Clean the project (mvn clean)")
+    fmt.Println("  ps           List processes started by marn")
+    fmt.Println("  stop [pid]   Stop processes started by marn")
+    fmt.Println("  logs [n]     List build logs or show one (--last for the newest)")
     fmt.Println("  watch        Watch for changes and rebuild")
+    fmt.Println("               --debug[=port][,suspend]  Debug port for 'marn run' post commands")
+    fmt.Println("               --verbose     Log ignored file events and why")
     fmt.Println("  version      Show version")
     fmt.Println("  <script>     Run custom script from pom.xml")
     fmt.Println()
+    fmt.Println("Options for install, link, build, test, package and clean:")
+    fmt.Println("  --format=json|sarif|github  Report compiler errors and test failures")
+    fmt.Println()
+    fmt.Println("Maven options for install, link, build, test, package, clean, watch, outdated, upgrade, why and list:")
+    fmt.Println("  -P <profiles>, -D<name>=<value>, -T <threads>, -s <settings>, -o, -U")
+    fmt.Println("  -- <args>    Pass the remaining arguments to Maven")
+    fmt.Println()
     fmt.Println("Custom scripts are defined in pom.xml under <properties>:")
     fmt.Println("  <script.myScript>mvn compile</script.myScript>")
     fmt.Println()
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Maven executables selected with marn.maven
+const (
+	mavenAuto    = "auto"
+	mavenWrapper = "mvnw"
+	mavenDaemon  = "mvnd"
+	mavenPlain   = "mvn"
+)
+
+// Maven is the Maven executable used to build a project
+type Maven struct {
+	Name string
+	Path string
+	Args []string
+}
+
+// mavenWarning makes sure a bad marn.maven is only reported once
+var mavenWarning sync.Once
+
+// colorCodes matches the ANSI color codes in colored Maven output
+var colorCodes = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// cliMavenArgs are the Maven flags given on the marn command line
+var cliMavenArgs []string
+
+// findMaven picks the Maven executable for a project directory
+// By default the Maven wrapper wins, then the Maven daemon, then the installed mvn
+func findMaven(projectDir string) Maven {
+	mode := getProperty("marn.maven")
+	if mode == "" {
+		mode = mavenAuto
+	}
+
+	switch mode {
+	case mavenAuto:
+
+		if maven, ok := findMavenWrapper(projectDir); ok {
+			return maven
+		}
+
+		if maven, ok := findMavenOnPath(mavenDaemon); ok {
+			return maven
+		}
+
+	case mavenWrapper:
+
+		if maven, ok := findMavenWrapper(projectDir); ok {
+			return maven
+		}
+
+		mavenWarning.Do(func() {
+			fmt.Fprintf(console, "%sWarning: No Maven wrapper found above %s, using mvn%s\n", colors.Yellow, projectDir, colors.Reset)
+		})
+
+	case mavenDaemon:
+
+		if maven, ok := findMavenOnPath(mavenDaemon); ok {
+			return maven
+		}
+
+		mavenWarning.Do(func() {
+			fmt.Fprintf(console, "%sWarning: mvnd is not installed, using mvn%s\n", colors.Yellow, colors.Reset)
+		})
+
+	case mavenPlain:
+		// The installed mvn below
+
+	default:
+		// Any other value is the Maven command itself
+		return Maven{Name: mode, Path: mode}
+	}
+
+	maven, _ := findMavenOnPath(mavenPlain)
+	return maven
+}
+
+// findMavenWrapper looks for mvnw in the project directory and its parents
+func findMavenWrapper(projectDir string) (Maven, bool) {
+	name := "mvnw"
+	if isWindows() {
+		name = "mvnw.cmd"
+	}
+
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return Maven{}, false
+	}
+
+	for {
+		path := filepath.Join(dir, name)
+
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			maven := Maven{Name: mavenDisplayPath(path), Path: path}
+
+			// Wrappers checked out without the executable bit still run through sh
+			if !isWindows() && info.Mode()&0111 == 0 {
+				maven.Path = "sh"
+				maven.Args = []string{path}
+			}
+
+			return maven, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Maven{}, false
+		}
+
+		dir = parent
+	}
+}
+
+// findMavenOnPath looks for mvn or mvnd on the PATH
+// Returns the plain name when it is not installed, so running it reports the error
+func findMavenOnPath(name string) (Maven, bool) {
+
+	// Windows installs ship .cmd scripts
+	if isWindows() {
+
+		if _, err := exec.LookPath(name + ".cmd"); err == nil {
+			return Maven{Name: name, Path: name + ".cmd"}, true
+		}
+	}
+
+	if _, err := exec.LookPath(name); err == nil {
+		return Maven{Name: name, Path: name}, true
+	}
+
+	return Maven{Name: name, Path: name}, false
+}
+
+// mavenDisplayPath returns a wrapper path relative to the current project, like ./mvnw
+func mavenDisplayPath(path string) string {
+	rel, err := filepath.Rel(currentDir, path)
+	if err != nil {
+		return path
+	}
+
+	if filepath.Base(rel) == rel {
+		return "." + string(filepath.Separator) + rel
+	}
+
+	return rel
+}
+
+// Command creates the command that runs Maven with args in a project directory
+// The project and user Maven arguments are added after args
+func (m Maven) Command(ctx context.Context, projectDir string, args ...string) *exec.Cmd {
+	args = append(append(append([]string{}, m.Args...), args...), getMavenArgs()...)
+
+	cmd := exec.CommandContext(ctx, m.Path, args...)
+	cmd.Dir = projectDir
+
+	// MAVEN_ARGS is already on the command line, newer Maven versions would add it twice
+	cmd.Env = removeEnv(os.Environ(), "MAVEN_ARGS")
+
+	return cmd
+}
+
+// mavenColorArgs makes Maven color its output when marn shows it on a terminal
+// Maven writes into pipes that also feed the build log, so it can't detect the terminal itself
+func mavenColorArgs(out *os.File) []string {
+
+	if !isTerminal(out) {
+		return nil
+	}
+
+	for _, arg := range getMavenArgs() {
+
+		if strings.HasPrefix(arg, "-Dstyle.color=") {
+			return nil
+		}
+	}
+
+	return []string{"-Dstyle.color=always"}
+}
+
+// stripColors removes ANSI color codes from Maven output
+func stripColors(text string) string {
+	return colorCodes.ReplaceAllString(text, "")
+}
+
+// getMavenArgs returns the extra arguments for every Maven invocation
+// Project defaults come first, then MAVEN_ARGS, MARN_MAVEN_ARGS and the command line
+func getMavenArgs() []string {
+	var args []string
+
+	args = append(args, strings.Fields(getProperty("marn.mavenArgs"))...)
+	args = append(args, strings.Fields(os.Getenv("MAVEN_ARGS"))...)
+	args = append(args, strings.Fields(os.Getenv("MARN_MAVEN_ARGS"))...)
+	args = append(args, cliMavenArgs...)
+
+	// Local dependencies are built in their own directory
+	for i, arg := range args {
+
+		switch {
+		case (arg == "-s" || arg == "--settings") && i+1 < len(args):
+			args[i+1] = absoluteProjectPath(args[i+1])
+
+		case strings.HasPrefix(arg, "--settings="):
+			args[i] = "--settings=" + absoluteProjectPath(strings.TrimPrefix(arg, "--settings="))
+		}
+	}
+
+	return args
+}
+
+// parseMavenArgs takes the Maven flags out of a marn command line into cliMavenArgs
+// Arguments after -- go to Maven too
+func parseMavenArgs(args []string) ([]string, error) {
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			cliMavenArgs = append(cliMavenArgs, args[i+1:]...)
+			return rest, nil
+
+		case arg == "-P" || arg == "-D" || arg == "-T" || arg == "-s" || arg == "--settings":
+
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option '%s' needs a value", arg)
+			}
+
+			cliMavenArgs = append(cliMavenArgs, arg, args[i+1])
+			i++
+
+		case arg == "-o" || arg == "--offline" || arg == "-U" || arg == "--update-snapshots",
+			strings.HasPrefix(arg, "-P") || strings.HasPrefix(arg, "-D") || strings.HasPrefix(arg, "-T"),
+			strings.HasPrefix(arg, "--settings="):
+			cliMavenArgs = append(cliMavenArgs, arg)
+
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	return rest, nil
+}
+
+// absoluteProjectPath resolves a path relative to the current project
+func absoluteProjectPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(currentDir, path)
+}
+
+// removeEnv returns an environment without a variable
+func removeEnv(env []string, name string) []string {
+	var result []string
+
+	for _, entry := range env {
+
+		if !strings.HasPrefix(entry, name+"=") {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
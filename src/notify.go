@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification modes for watch.notify
+const (
+	notifyAuto     = "auto"
+	notifyDesktop  = "desktop"
+	notifyTerminal = "terminal"
+)
+
+// BuildError is a failed Maven build with the diagnostics found in its output
+type BuildError struct {
+	Err         error
+	Diagnostics []Diagnostic
+}
+
+// Error returns the error of the Maven process
+func (e *BuildError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the Maven process
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// parseNotifyMode parses the watch.notify property
+func parseNotifyMode(value string) (string, error) {
+	switch value {
+	case "", "false":
+		return "", nil
+	case "true", notifyAuto:
+		return notifyAuto, nil
+	case notifyDesktop, notifyTerminal:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown watch.notify '%s'", value)
+	}
+}
+
+// notifyBuild reports a finished watch build in the terminal title and as a notification
+func notifyBuild(mode string, success bool, err error, elapsed time.Duration) {
+	if mode == "" {
+		return
+	}
+
+	project := getPomArtifactID(filepath.Join(currentDir, "pom.xml"))
+	if project == "" {
+		project = filepath.Base(currentDir)
+	}
+
+	duration := fmt.Sprintf("%.1fs", elapsed.Seconds())
+	status, title, message := "✓", "Build successful", "Built in "+duration
+
+	if !success {
+		status, title, message = "✗", "Build failed", "Failed after "+duration
+
+		if first := firstBuildError(err); first != "" {
+			message = first
+		}
+	}
+
+	terminal := isTerminal(os.Stdout)
+
+	if terminal {
+		setTerminalTitle(fmt.Sprintf("%s %s (%s)", status, project, duration))
+	}
+
+	title = project + ": " + title
+
+	// Auto prefers the desktop and falls back to the terminal
+	if mode == notifyDesktop || mode == notifyAuto {
+
+		if sendDesktopNotification(title, message, success) {
+			return
+		}
+	}
+
+	if terminal && mode != notifyDesktop {
+		sendTerminalNotification(title, message)
+	}
+}
+
+// firstBuildError returns the first error of a failed build as a single line
+func firstBuildError(err error) string {
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		return ""
+	}
+
+	for _, d := range buildErr.Diagnostics {
+
+		if d.Severity != "error" {
+			continue
+		}
+
+		message := strings.TrimSpace(strings.SplitN(d.Message, "\n", 2)[0])
+
+		if location := d.Location(); location != "" {
+			return location + ": " + message
+		}
+
+		return message
+	}
+
+	return ""
+}
+
+// terminalTitle tracks whether the original terminal title was saved
+var terminalTitle struct {
+	mu    sync.Mutex
+	saved bool
+}
+
+// setTerminalTitle sets the title of the terminal window with OSC 0
+// The first call saves the current title on the terminal's title stack
+func setTerminalTitle(title string) {
+	terminalTitle.mu.Lock()
+	defer terminalTitle.mu.Unlock()
+
+	if !terminalTitle.saved {
+		fmt.Print("\x1b[22;0t")
+		terminalTitle.saved = true
+	}
+
+	fmt.Printf("\x1b]0;%s\x07", sanitizeEscapeText(title))
+}
+
+// restoreTerminalTitle removes the build status from the terminal title
+// The title is cleared, terminals with a title stack then get the saved title back
+func restoreTerminalTitle() {
+	terminalTitle.mu.Lock()
+	defer terminalTitle.mu.Unlock()
+
+	if !terminalTitle.saved {
+		return
+	}
+
+	fmt.Print("\x1b]0;\x07\x1b[23;0t")
+	terminalTitle.saved = false
+}
+
+// sendTerminalNotification asks the terminal to show a notification
+// VTE based terminals, urxvt and foot understand OSC 777, most others OSC 9
+func sendTerminalNotification(title, message string) {
+	term := os.Getenv("TERM")
+
+	if os.Getenv("VTE_VERSION") != "" || strings.HasPrefix(term, "rxvt") || strings.HasPrefix(term, "foot") {
+		fmt.Printf("\x1b]777;notify;%s;%s\x07", strings.ReplaceAll(sanitizeEscapeText(title), ";", ","), sanitizeEscapeText(message))
+		return
+	}
+
+	fmt.Printf("\x1b]9;%s\x07", sanitizeEscapeText(title+": "+message))
+}
+
+// sendDesktopNotification shows a notification with notify-send
+// Returns false when notify-send is not available
+func sendDesktopNotification(title, message string, success bool) bool {
+	notifySend, err := exec.LookPath("notify-send")
+	if err != nil {
+		return false
+	}
+
+	icon := "dialog-information"
+	if !success {
+		icon = "dialog-error"
+	}
+
+	cmd := exec.Command(notifySend, "--app-name=marn", "--icon="+icon, title, message)
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+
+	// Don't wait for the notification daemon
+	go cmd.Wait()
+
+	return true
+}
+
+// sanitizeEscapeText removes control characters that would end an escape sequence early
+func sanitizeEscapeText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+
+		return r
+	}, text)
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Types of the artifacts in the 'marn outdated' report
+const (
+	outdatedDependency = "dependency"
+	outdatedManaged    = "managed"
+	outdatedPlugin     = "plugin"
+)
+
+// outdatedLookups limits how many artifacts are looked up at the same time
+const outdatedLookups = 8
+
+// OutdatedEntry is a dependency or plugin in the 'marn outdated' report
+type OutdatedEntry struct {
+	Type        string `json:"type"`
+	GroupID     string `json:"groupId"`
+	ArtifactID  string `json:"artifactId"`
+	Scope       string `json:"scope,omitempty"`
+	Declared    string `json:"declared,omitempty"`  // version as written, like ${guava.version} or [1.0,2.0)
+	Property    string `json:"property,omitempty"`  // property the version comes from
+	ManagedIn   string `json:"managedIn,omitempty"` // pom.xml whose dependencyManagement or pluginManagement sets the version
+	Current     string `json:"current,omitempty"`
+	LatestPatch string `json:"latestPatch,omitempty"`
+	LatestMinor string `json:"latestMinor,omitempty"`
+	LatestMajor string `json:"latestMajor,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	managedPath string // file of ManagedIn
+	external    bool   // ManagedIn is a pom in the local repository, it is never edited
+}
+
+// OutdatedOptions holds the options passed to 'marn outdated'
+type OutdatedOptions struct {
+	JSON bool
+}
+
+// projectPom is a parsed pom.xml file
+type projectPom struct {
+	Path string
+	POM  POM
+
+	// Artifact is group:artifact:version for poms read from the local repository
+	Artifact string
+}
+
+// managedVersion is a version from dependencyManagement or pluginManagement
+type managedVersion struct {
+	Version string
+	Pom     projectPom
+}
+
+// Name returns the project path of a pom, or its coordinates when it comes from the local repository
+func (p projectPom) Name() string {
+	if p.Artifact != "" {
+		return p.Artifact
+	}
+
+	return displayPath(p.Path)
+}
+
+// Key returns the entry as group:artifact
+func (e *OutdatedEntry) Key() string {
+	return e.GroupID + ":" + e.ArtifactID
+}
+
+// HasUpdate reports whether a newer version exists
+func (e *OutdatedEntry) HasUpdate() bool {
+	return e.LatestMajor != ""
+}
+
+// parseOutdatedArgs parses the arguments given to 'marn outdated'
+func parseOutdatedArgs(args []string) (OutdatedOptions, error) {
+	var opts OutdatedOptions
+
+	for _, arg := range args {
+
+		switch arg {
+		case "--json":
+			opts.JSON = true
+
+		default:
+			return opts, fmt.Errorf("unknown option '%s'", arg)
+		}
+	}
+
+	return opts, nil
+}
+
+// showOutdated implements 'marn outdated', listing the newer versions of dependencies and plugins
+func showOutdated() {
+	opts, err := parseOutdatedArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	poms, err := readProjectPoms()
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	entries := collectOutdatedEntries(poms)
+
+	if !opts.JSON {
+		fmt.Printf("%sChecking %d artifacts...%s\n", colors.Blue, len(entries), colors.Reset)
+	}
+
+	checkOutdatedEntries(entries)
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(entries)
+		return
+	}
+
+	fmt.Println()
+	printOutdatedReport(entries)
+}
+
+// readProjectPoms reads pom.xml and its parents, nearest first
+// Parents that are not part of the project, like spring-boot-starter-parent, come from the local repository
+func readProjectPoms() ([]projectPom, error) {
+	var poms []projectPom
+
+	for i, path := range append([]string{pomFile}, getParentPoms()...) {
+		content, err := os.ReadFile(path)
+		if err != nil && i == 0 {
+			return nil, fmt.Errorf("could not read pom.xml: %v", err)
+		}
+
+		var pom POM
+		if err := xml.Unmarshal(content, &pom); err != nil {
+
+			if i == 0 {
+				return nil, fmt.Errorf("could not parse pom.xml: %v", err)
+			}
+
+			continue
+		}
+
+		poms = append(poms, projectPom{Path: path, POM: pom})
+	}
+
+	return append(poms, readRepositoryParents(poms[len(poms)-1].POM)...), nil
+}
+
+// readRepositoryPom reads the pom of an artifact version from the local repository
+func readRepositoryPom(groupID, artifactID, version string) (projectPom, bool) {
+	path := repositoryPomPath(groupID, artifactID, version)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return projectPom{}, false
+	}
+
+	var pom POM
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return projectPom{}, false
+	}
+
+	return projectPom{Path: path, POM: pom, Artifact: groupID + ":" + artifactID + ":" + version}, true
+}
+
+// readRepositoryParents follows the parents of a pom through the local repository
+// It stops at the first parent that was not downloaded yet
+func readRepositoryParents(pom POM) []projectPom {
+	var parents []projectPom
+	seen := make(map[string]bool)
+
+	for pom.Parent.ArtifactID != "" {
+		parent := pom.Parent
+		key := parent.GroupID + ":" + parent.ArtifactID + ":" + parent.Version
+
+		if seen[key] || strings.Contains(key, "${") {
+			break
+		}
+
+		seen[key] = true
+
+		p, ok := readRepositoryPom(parent.GroupID, parent.ArtifactID, parent.Version)
+		if !ok {
+			break
+		}
+
+		parents = append(parents, p)
+		pom = p.POM
+	}
+
+	return parents
+}
+
+// addImportedDependencies adds the dependencyManagement of imported BOMs from the local repository
+// Versions in a BOM are expanded with the properties of the BOM, the first import of an artifact wins
+func addImportedDependencies(managed map[string]managedVersion, deps []Dependency, properties map[string]string, seen map[string]bool) {
+	for _, dep := range deps {
+
+		if dep.Scope != "import" {
+			continue
+		}
+
+		groupID := expandProperties(dep.GroupID, properties)
+		version := expandProperties(dep.Version, properties)
+
+		if seen[groupID+":"+dep.ArtifactID+":"+version] {
+			continue
+		}
+
+		seen[groupID+":"+dep.ArtifactID+":"+version] = true
+
+		bom, ok := readRepositoryPom(groupID, dep.ArtifactID, version)
+		if !ok {
+			continue
+		}
+
+		boms := append([]projectPom{bom}, readRepositoryParents(bom.POM)...)
+		bomProperties := getPomProperties(boms)
+
+		for _, p := range boms {
+
+			for _, managedDep := range p.POM.DependencyManagement.Dependencies.Dependency {
+				key := expandProperties(managedDep.GroupID, bomProperties) + ":" + managedDep.ArtifactID
+
+				if _, ok := managed[key]; !ok && managedDep.Scope != "import" {
+					managed[key] = managedVersion{Version: expandProperties(managedDep.Version, bomProperties), Pom: p}
+				}
+			}
+		}
+
+		// BOMs can import other BOMs
+		for _, p := range boms {
+			addImportedDependencies(managed, p.POM.DependencyManagement.Dependencies.Dependency, bomProperties, seen)
+		}
+	}
+}
+
+// getPomProperties returns the properties of pom.xml and its parents, nearer poms win
+// The project.* properties Maven defines for versions are included
+func getPomProperties(poms []projectPom) map[string]string {
+	properties := make(map[string]string)
+
+	for i := len(poms) - 1; i >= 0; i-- {
+
+		for name, value := range poms[i].POM.Properties.Values() {
+			properties[name] = value
+		}
+	}
+
+	pom := poms[0].POM
+
+	version := pom.Version
+	if version == "" {
+		version = pom.Parent.Version
+	}
+
+	groupID := pom.GroupID
+	if groupID == "" {
+		groupID = pom.Parent.GroupID
+	}
+
+	properties["project.version"] = version
+	properties["project.groupId"] = groupID
+	properties["project.artifactId"] = pom.ArtifactID
+	properties["project.parent.version"] = pom.Parent.Version
+	properties["project.parent.groupId"] = pom.Parent.GroupID
+
+	return properties
+}
+
+// collectOutdatedEntries lists the dependencies and plugins of pom.xml with their current versions
+// Versions left out are taken from the dependencyManagement and pluginManagement of the pom and its parents
+func collectOutdatedEntries(poms []projectPom) []*OutdatedEntry {
+	properties := getPomProperties(poms)
+
+	managedDependencies := make(map[string]managedVersion)
+	managedPlugins := make(map[string]managedVersion)
+
+	for _, p := range poms {
+
+		for _, dep := range p.POM.DependencyManagement.Dependencies.Dependency {
+			key := expandProperties(dep.GroupID, properties) + ":" + dep.ArtifactID
+
+			if _, ok := managedDependencies[key]; !ok && dep.Scope != "import" {
+				managedDependencies[key] = managedVersion{Version: dep.Version, Pom: p}
+			}
+		}
+
+		for _, plugin := range p.POM.Build.PluginManagement.Plugins.Plugin {
+			key := pluginGroupID(plugin) + ":" + plugin.ArtifactID
+
+			if _, ok := managedPlugins[key]; !ok {
+				managedPlugins[key] = managedVersion{Version: plugin.Version, Pom: p}
+			}
+		}
+	}
+
+	// Imported BOMs come after the versions the poms manage themselves, like in Maven
+	seenBoms := make(map[string]bool)
+
+	for _, p := range poms {
+		addImportedDependencies(managedDependencies, p.POM.DependencyManagement.Dependencies.Dependency, properties, seenBoms)
+	}
+
+	var entries []*OutdatedEntry
+	seen := make(map[string]bool)
+
+	add := func(entryType, groupID, artifactID, scope, version string, managed map[string]managedVersion) {
+		entry := &OutdatedEntry{
+			Type:       entryType,
+			GroupID:    expandProperties(groupID, properties),
+			ArtifactID: artifactID,
+			Scope:      scope,
+			Declared:   version,
+		}
+
+		if seen[entryType+" "+entry.Key()] {
+			return
+		}
+
+		seen[entryType+" "+entry.Key()] = true
+
+		if m, ok := managed[entry.Key()]; ok && version == "" {
+			entry.Declared = m.Version
+			entry.ManagedIn = m.Pom.Name()
+			entry.managedPath = m.Pom.Path
+			entry.external = m.Pom.Artifact != ""
+		}
+
+		entry.Property = versionProperty(entry.Declared)
+		entry.Current = expandProperties(entry.Declared, properties)
+
+		switch {
+		case entry.Current == "" && entryType == outdatedPlugin:
+			entry.Error = "no version set, Maven picks a default"
+
+		case entry.Current == "":
+			entry.Error = "no version set, it is managed outside the project"
+
+		case strings.Contains(entry.Current, "${"):
+			entry.Error = fmt.Sprintf("could not resolve %s", entry.Current)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	pom := poms[0].POM
+
+	for _, dep := range pom.Dependencies.Dependency {
+		add(outdatedDependency, dep.GroupID, dep.ArtifactID, dep.Scope, dep.Version, managedDependencies)
+	}
+
+	for _, dep := range pom.DependencyManagement.Dependencies.Dependency {
+		add(outdatedManaged, dep.GroupID, dep.ArtifactID, dep.Scope, dep.Version, nil)
+	}
+
+	for _, plugin := range pom.Build.Plugins.Plugin {
+		add(outdatedPlugin, pluginGroupID(plugin), plugin.ArtifactID, "", plugin.Version, managedPlugins)
+	}
+
+	for _, plugin := range pom.Build.PluginManagement.Plugins.Plugin {
+		add(outdatedPlugin, pluginGroupID(plugin), plugin.ArtifactID, "", plugin.Version, nil)
+	}
+
+	return entries
+}
+
+// pluginGroupID returns the group of a plugin, Maven's own plugins may leave it out
+func pluginGroupID(plugin Plugin) string {
+	if plugin.GroupID == "" {
+		return defaultPluginGroupID
+	}
+
+	return plugin.GroupID
+}
+
+// checkOutdatedEntries looks up the available versions of every entry
+// Lookups run in parallel since the remote repository is slow
+func checkOutdatedEntries(entries []*OutdatedEntry) {
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, outdatedLookups)
+
+	for _, entry := range entries {
+
+		if entry.Error != "" {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(entry *OutdatedEntry) {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			versions, err := getAvailableVersions(entry.GroupID, entry.ArtifactID)
+			if err != nil {
+				entry.Error = err.Error()
+				return
+			}
+
+			if len(versions) == 0 {
+				entry.Error = "no versions found"
+				return
+			}
+
+			// A range resolves to the newest version it allows
+			if isVersionRange(entry.Current) {
+				current := latestInRange(entry.Current, versions)
+
+				if current == "" {
+					entry.Error = fmt.Sprintf("no version matches %s", entry.Current)
+					return
+				}
+
+				entry.Current = current
+			}
+
+			entry.LatestPatch, entry.LatestMinor, entry.LatestMajor = latestUpdates(entry.Current, versions)
+		}(entry)
+	}
+
+	wg.Wait()
+}
+
+// printOutdatedReport prints the entries as tables of dependencies, managed dependencies and plugins
+func printOutdatedReport(entries []*OutdatedEntry) {
+	headers := []string{"Package", "Current", "Patch", "Minor", "Major", "Version from"}
+	widths := make([]int, len(headers))
+
+	rows := make(map[*OutdatedEntry][]string)
+
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+
+	for _, entry := range entries {
+
+		if entry.Error != "" {
+			continue
+		}
+
+		name := entry.Key()
+		if entry.Scope != "" && entry.Scope != "compile" {
+			name += " (" + entry.Scope + ")"
+		}
+
+		row := []string{name, entry.Current, orDash(entry.LatestPatch), orDash(entry.LatestMinor), orDash(entry.LatestMajor), versionSource(entry)}
+
+		for i, cell := range row {
+
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+
+		rows[entry] = row
+	}
+
+	sections := []struct {
+		title     string
+		entryType string
+	}{
+		{"Dependencies", outdatedDependency},
+		{"Managed dependencies", outdatedManaged},
+		{"Plugins", outdatedPlugin},
+	}
+
+	cellColors := []string{"", "", colors.Green, colors.Yellow, colors.Red, ""}
+
+	for _, section := range sections {
+		printed := false
+
+		for _, entry := range entries {
+			row, ok := rows[entry]
+
+			if !ok || entry.Type != section.entryType {
+				continue
+			}
+
+			if !printed {
+				fmt.Printf("%s%s%s\n", colors.Blue, section.title, colors.Reset)
+				printOutdatedRow(headers, widths, make([]string, len(headers)))
+				printed = true
+			}
+
+			printOutdatedRow(row, widths, cellColors)
+		}
+
+		if printed {
+			fmt.Println()
+		}
+	}
+
+	checked := 0
+	outdated := 0
+
+	for _, entry := range entries {
+
+		if entry.Error != "" {
+			fmt.Printf("%sCould not check %s: %s%s\n", colors.Yellow, entry.Key(), entry.Error, colors.Reset)
+			continue
+		}
+
+		checked++
+
+		if entry.HasUpdate() {
+			outdated++
+		}
+	}
+
+	if outdated == 0 {
+		fmt.Printf("%s✓ All %d artifacts are up to date%s\n", colors.Green, checked, colors.Reset)
+		return
+	}
+
+	fmt.Printf("%s%d of %d artifacts have newer versions%s\n", colors.Yellow, outdated, checked, colors.Reset)
+}
+
+// printOutdatedRow prints a row of the report with padded and colored cells
+// Cells are padded before coloring, escape codes would break the alignment
+func printOutdatedRow(cells []string, widths []int, cellColors []string) {
+	var line strings.Builder
+	line.WriteString("  ")
+
+	for i, cell := range cells {
+		padded := cell + strings.Repeat(" ", widths[i]-len(cell)+2)
+
+		if cellColors[i] != "" && cell != "-" {
+			padded = cellColors[i] + cell + colors.Reset + strings.Repeat(" ", widths[i]-len(cell)+2)
+		}
+
+		line.WriteString(padded)
+	}
+
+	fmt.Println(strings.TrimRight(line.String(), " "))
+}
+
+// versionSource describes where the version of an entry comes from
+func versionSource(entry *OutdatedEntry) string {
+	var parts []string
+
+	if entry.Property != "" {
+		parts = append(parts, "${"+entry.Property+"}")
+	} else if isVersionRange(entry.Declared) {
+		parts = append(parts, entry.Declared)
+	}
+
+	if entry.ManagedIn != "" {
+		parts = append(parts, "managed in "+entry.ManagedIn)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// orDash returns "-" for an empty string
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// repositoryMetadata is served by the test repositories, by artifact path
+var repositoryMetadata = map[string][]string{
+	"com/google/guava/guava":                         {"31.1-jre", "32.1.2-jre", "32.1.3-jre", "33.0.0-jre", "33.1.0-android", "33.1.0-jre"},
+	"org/example/core":                               {"2.0", "2.0.1", "2.1", "3.0-RC1"},
+	"org/example/ranged":                             {"3.9", "3.10", "3.12.0", "4.0"},
+	"org/apache/maven/plugins/maven-compiler-plugin": {"3.10.1", "3.11.0", "3.13.0"},
+	"org/junit/jupiter/junit-jupiter":                {"5.9.0", "5.9.3", "5.10.1"},
+}
+
+// metadataXML returns a maven-metadata.xml listing versions
+func metadataXML(versions []string) string {
+	var b strings.Builder
+	b.WriteString("<metadata><versioning><versions>")
+
+	for _, version := range versions {
+		b.WriteString("<version>" + version + "</version>")
+	}
+
+	b.WriteString("</versions></versioning></metadata>")
+	return b.String()
+}
+
+// newTestRepository serves repositoryMetadata over HTTP, /broken/ answers with an error
+func newTestRepository(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/maven-metadata.xml")
+
+		if strings.HasPrefix(path, "broken/") {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+
+		versions, ok := repositoryMetadata[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(metadataXML(versions)))
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeTestFile writes a file, creating its directory
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchRemoteVersions(t *testing.T) {
+	server := newTestRepository(t)
+
+	// A file:// repository is the same layout on disk
+	dir := t.TempDir()
+	for path, versions := range repositoryMetadata {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(path), "maven-metadata.xml"), metadataXML(versions))
+	}
+
+	for _, url := range []string{server.URL, "file://" + filepath.ToSlash(dir)} {
+		versions, err := fetchRemoteVersions(url, "com.google.guava", "guava")
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+
+		if !reflect.DeepEqual(versions, repositoryMetadata["com/google/guava/guava"]) {
+			t.Errorf("%s: got %q", url, versions)
+		}
+
+		// A missing artifact is not an error
+		versions, err = fetchRemoteVersions(url, "org.example", "missing")
+		if err != nil || versions != nil {
+			t.Errorf("%s: missing artifact returned %q, %v", url, versions, err)
+		}
+	}
+
+	if _, err := fetchRemoteVersions(server.URL, "broken", "artifact"); err == nil {
+		t.Error("expected an error for a failing repository")
+	}
+}
+
+func TestOutdatedLookup(t *testing.T) {
+	server := newTestRepository(t)
+	home := t.TempDir()
+	project := t.TempDir()
+
+	t.Setenv("HOME", home)
+	t.Setenv("MARN_REPOSITORY", server.URL)
+	t.Setenv("MAVEN_ARGS", "")
+	t.Setenv("MARN_MAVEN_ARGS", "")
+
+	oldDir, oldPom := currentDir, pomFile
+	currentDir, pomFile = project, filepath.Join(project, "pom.xml")
+	t.Cleanup(func() { currentDir, pomFile = oldDir, oldPom })
+
+	repository := filepath.Join(home, ".m2", "repository")
+
+	// The parent only exists in the local repository and imports a BOM
+	writeTestFile(t, filepath.Join(repository, "org/example/example-parent/1.0/example-parent-1.0.pom"), `<project>
+  <groupId>org.example</groupId>
+  <artifactId>example-parent</artifactId>
+  <version>1.0</version>
+  <properties>
+    <bom.version>2.0</bom.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>org.example</groupId>
+        <artifactId>example-bom</artifactId>
+        <version>${bom.version}</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <build>
+    <pluginManagement>
+      <plugins>
+        <plugin>
+          <artifactId>maven-compiler-plugin</artifactId>
+          <version>3.10.1</version>
+        </plugin>
+      </plugins>
+    </pluginManagement>
+  </build>
+</project>`)
+
+	writeTestFile(t, filepath.Join(repository, "org/example/example-bom/2.0/example-bom-2.0.pom"), `<project>
+  <groupId>org.example</groupId>
+  <artifactId>example-bom</artifactId>
+  <version>2.0</version>
+  <properties>
+    <core.version>2.0</core.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>org.example</groupId>
+        <artifactId>core</artifactId>
+        <version>${core.version}</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+</project>`)
+
+	writeTestFile(t, pomFile, `<project>
+  <parent>
+    <groupId>org.example</groupId>
+    <artifactId>example-parent</artifactId>
+    <version>1.0</version>
+    <relativePath/>
+  </parent>
+  <artifactId>app</artifactId>
+  <properties>
+    <guava.version>32.1.2-jre</guava.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>com.google.guava</groupId>
+      <artifactId>guava</artifactId>
+      <version>${guava.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>org.example</groupId>
+      <artifactId>core</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>org.example</groupId>
+      <artifactId>ranged</artifactId>
+      <version>[3.10,4.0)</version>
+    </dependency>
+    <dependency>
+      <groupId>org.junit.jupiter</groupId>
+      <artifactId>junit-jupiter</artifactId>
+      <version>5.10.1</version>
+      <scope>test</scope>
+    </dependency>
+    <dependency>
+      <groupId>org.example</groupId>
+      <artifactId>unknown</artifactId>
+      <version>1.0</version>
+    </dependency>
+  </dependencies>
+  <build>
+    <plugins>
+      <plugin>
+        <artifactId>maven-compiler-plugin</artifactId>
+      </plugin>
+    </plugins>
+  </build>
+</project>`)
+
+	poms, err := readProjectPoms()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(poms) != 2 || poms[1].Artifact != "org.example:example-parent:1.0" {
+		t.Fatalf("expected the project pom and the repository parent, got %d poms", len(poms))
+	}
+
+	entries := collectOutdatedEntries(poms)
+	checkOutdatedEntries(entries)
+
+	type result struct {
+		Current, Patch, Minor, Major, ManagedIn, Property, Error string
+		External                                                 bool
+	}
+
+	// BOM versions are expanded with the properties of the BOM, the project can't override them
+	want := map[string]result{
+		"dependency com.google.guava:guava":                     {Current: "32.1.2-jre", Patch: "32.1.3-jre", Minor: "32.1.3-jre", Major: "33.1.0-jre", Property: "guava.version"},
+		"dependency org.example:core":                           {Current: "2.0", Patch: "2.0.1", Minor: "2.1", Major: "2.1", ManagedIn: "org.example:example-bom:2.0", External: true},
+		"dependency org.example:ranged":                         {Current: "3.12.0", Major: "4.0"},
+		"dependency org.junit.jupiter:junit-jupiter":            {Current: "5.10.1"},
+		"dependency org.example:unknown":                        {Current: "1.0", Error: "no versions found"},
+		"plugin org.apache.maven.plugins:maven-compiler-plugin": {Current: "3.10.1", Minor: "3.13.0", Major: "3.13.0", ManagedIn: "org.example:example-parent:1.0", External: true},
+	}
+
+	if len(entries) != len(want) {
+		t.Errorf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for _, entry := range entries {
+		key := entry.Type + " " + entry.Key()
+
+		got := result{
+			Current:   entry.Current,
+			Patch:     entry.LatestPatch,
+			Minor:     entry.LatestMinor,
+			Major:     entry.LatestMajor,
+			ManagedIn: entry.ManagedIn,
+			Property:  entry.Property,
+			Error:     entry.Error,
+			External:  entry.external,
+		}
+
+		if expected, ok := want[key]; !ok {
+			t.Errorf("unexpected entry %s", key)
+		} else if got != expected {
+			t.Errorf("%s:\n got  %+v\n want %+v", key, got, expected)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunRecord describes a process started by marn
+type RunRecord struct {
+	PID       int       `json:"pid"`
+	Source    string    `json:"source"` // "run" or "watch"
+	Group     bool      `json:"group"`  // true if the process leads its own process group
+	StartTime time.Time `json:"startTime"`
+	Command   []string  `json:"command"`
+}
+
+// PidFile stores the processes marn started for a project
+type PidFile struct {
+	Processes []RunRecord `json:"processes"`
+}
+
+// defaultStopGracePeriod is how long 'marn stop' waits before killing a process
+const defaultStopGracePeriod = 10 * time.Second
+
+// pidFileLockTimeout is how long to wait for another marn process to release the PID file
+const pidFileLockTimeout = 5 * time.Second
+
+// processStartTolerance is how far the start time of a process may differ from its record
+// ps only reports whole seconds
+const processStartTolerance = 2 * time.Second
+
+// pidFileStaleLock is the age after which a PID file lock is considered abandoned
+const pidFileStaleLock = 30 * time.Second
+
+// getPidFilePath returns the path to the PID file for a project
+func getPidFilePath(projectPath string) string {
+	return filepath.Join(projectPath, ".marn", "run.pid")
+}
+
+// loadPidFile loads the PID file from disk
+func loadPidFile() (*PidFile, error) {
+	data, err := os.ReadFile(getPidFilePath(currentDir))
+	if os.IsNotExist(err) {
+		return &PidFile{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var pidFile PidFile
+	if err := json.Unmarshal(data, &pidFile); err != nil {
+		return nil, err
+	}
+
+	return &pidFile, nil
+}
+
+// savePidFile saves the PID file to disk, removing it when no processes are left
+// The file is written to a temporary file and renamed, so readers never see a partial file
+func savePidFile(pidFile *PidFile) error {
+	pidFilePath := getPidFilePath(currentDir)
+
+	if len(pidFile.Processes) == 0 {
+		err := os.Remove(pidFilePath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pidFilePath), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(pidFile, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(pidFilePath), "run.pid.*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), pidFilePath)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+// lockPidFile takes the PID file lock, waiting while another marn process holds it
+// Returns a function that releases the lock
+func lockPidFile() (func(), error) {
+	lockPath := getPidFilePath(currentDir) + ".lock"
+
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(pidFileLockTimeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		// A lock left behind by a marn process that crashed is taken over
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > pidFileStaleLock {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is held by another marn process", lockPath)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// updatePidFile loads, changes and saves the PID file while holding its lock
+func updatePidFile(update func(pidFile *PidFile)) error {
+	unlock, err := lockPidFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pidFile, err := loadPidFile()
+	if err != nil {
+		return err
+	}
+
+	update(pidFile)
+
+	return savePidFile(pidFile)
+}
+
+// removeRecords removes the matching records from the PID file
+func removeRecords(match func(record RunRecord) bool) error {
+	return updatePidFile(func(pidFile *PidFile) {
+		var remaining []RunRecord
+
+		for _, record := range pidFile.Processes {
+
+			if !match(record) {
+				remaining = append(remaining, record)
+			}
+		}
+
+		pidFile.Processes = remaining
+	})
+}
+
+// recordProcess adds a started process to the PID file
+func recordProcess(pid int, source string, group bool, command []string) {
+	// The real start time is what isRecordAlive compares against later
+	startTime, err := getProcessStartTime(pid)
+	if err != nil {
+		startTime = time.Now()
+	}
+
+	record := RunRecord{
+		PID:       pid,
+		Source:    source,
+		Group:     group,
+		StartTime: startTime,
+		Command:   command,
+	}
+
+	err = updatePidFile(func(pidFile *PidFile) {
+		pidFile.Processes = append(pidFile.Processes, record)
+	})
+
+	if err != nil {
+		fmt.Printf("%sWarning: Could not write PID file: %v%s\n", colors.Yellow, err, colors.Reset)
+	}
+}
+
+// forgetProcess removes a process from the PID file
+func forgetProcess(pid int) {
+	removeRecords(func(record RunRecord) bool {
+		return record.PID == pid
+	})
+}
+
+// isRecordAlive checks if a recorded process is still the one marn started
+// A process that started at a different time is stale, its PID was reused
+func isRecordAlive(record RunRecord) bool {
+	if !processExists(record.PID) {
+		return false
+	}
+
+	startTime, err := getProcessStartTime(record.PID)
+	if err != nil || record.StartTime.IsZero() {
+		// Can't verify, trust the PID
+		return true
+	}
+
+	diff := startTime.Sub(record.StartTime)
+	return diff > -processStartTolerance && diff < processStartTolerance
+}
+
+// stopRecord terminates a recorded process and kills it after the grace period
+// Returns false if the process is still running afterwards
+func stopRecord(record RunRecord, grace time.Duration) bool {
+	fmt.Printf("%sStopping %s process (pid %d)...%s\n", colors.Yellow, record.Source, record.PID, colors.Reset)
+
+	if err := terminatePid(record.PID, record.Group); err == nil {
+
+		if waitForExit(record.PID, grace) {
+			return true
+		}
+
+		fmt.Printf("%sProcess %d did not stop within %v, killing it%s\n", colors.Yellow, record.PID, grace, colors.Reset)
+	}
+
+	killPid(record.PID, record.Group)
+
+	if !waitForExit(record.PID, time.Second) {
+		fmt.Printf("%sError: Could not stop process %d%s\n", colors.Red, record.PID, colors.Reset)
+		return false
+	}
+
+	return true
+}
+
+// waitForExit waits up to timeout for a process to exit
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+
+		if !processExists(pid) {
+			return true
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return !processExists(pid)
+}
+
+// stopRecordedProcesses stops the live processes from a source and prunes stale entries
+// An empty source stops every recorded process
+// Returns how many processes were stopped and how many are still running
+func stopRecordedProcesses(source string, grace time.Duration) (int, int) {
+	pidFile, err := loadPidFile()
+	if err != nil {
+		fmt.Printf("%sWarning: Could not read PID file: %v%s\n", colors.Yellow, err, colors.Reset)
+		return 0, 0
+	}
+
+	stopped, failed := 0, 0
+
+	// The lock is not held while waiting for processes to exit
+	for _, record := range pidFile.Processes {
+
+		if source != "" && record.Source != source {
+			continue
+		}
+
+		if !isRecordAlive(record) {
+			continue
+		}
+
+		if stopRecord(record, grace) {
+			stopped++
+		} else {
+			failed++
+		}
+	}
+
+	// Records of processes that are still alive, including ones recorded meanwhile, are kept
+	err = removeRecords(func(record RunRecord) bool {
+		return !isRecordAlive(record)
+	})
+
+	if err != nil {
+		fmt.Printf("%sWarning: Could not update PID file: %v%s\n", colors.Yellow, err, colors.Reset)
+	}
+
+	return stopped, failed
+}
+
+// getStopGracePeriod returns the grace period from marn.run.gracePeriod
+func getStopGracePeriod() time.Duration {
+	return getDurationProperty("marn.run.gracePeriod", defaultStopGracePeriod)
+}
+
+// listProcesses shows the processes marn started for the project
+func listProcesses() {
+	pidFile, err := loadPidFile()
+	if err != nil {
+		fmt.Printf("%sError: Could not read PID file: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	if len(pidFile.Processes) == 0 {
+		fmt.Println("No processes started by marn are running.")
+		return
+	}
+
+	stale := make(map[int]bool)
+
+	for _, record := range pidFile.Processes {
+		uptime := time.Since(record.StartTime).Round(time.Second)
+
+		if isRecordAlive(record) {
+			fmt.Printf("%s%-8d%s %-6s %-10v %s\n", colors.Green, record.PID, colors.Reset, record.Source, uptime, strings.Join(record.Command, " "))
+		} else {
+			stale[record.PID] = true
+			fmt.Printf("%s%-8d%s %-6s %-10s %s\n", colors.Yellow, record.PID, colors.Reset, record.Source, "stale", strings.Join(record.Command, " "))
+		}
+	}
+
+	// Stale entries are shown once and then removed
+	if len(stale) > 0 {
+		removeRecords(func(record RunRecord) bool {
+			return stale[record.PID]
+		})
+	}
+}
+
+// stopProcesses stops the processes marn started for the project
+// With a PID argument only that process is stopped
+func stopProcesses() {
+	if len(os.Args) < 3 {
+		stopped, failed := stopRecordedProcesses("", getStopGracePeriod())
+
+		if stopped == 0 && failed == 0 {
+			fmt.Println("No processes started by marn are running.")
+		} else if stopped > 0 {
+			fmt.Printf("%s✓ Stopped %d process(es)%s\n", colors.Green, stopped, colors.Reset)
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	pid, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Printf("%sError: Invalid PID '%s'%s\n", colors.Red, os.Args[2], colors.Reset)
+		os.Exit(1)
+	}
+
+	pidFile, err := loadPidFile()
+	if err != nil {
+		fmt.Printf("%sError: Could not read PID file: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	for _, record := range pidFile.Processes {
+
+		if record.PID != pid {
+			continue
+		}
+
+		if !isRecordAlive(record) {
+			forgetProcess(pid)
+			fmt.Printf("%sProcess %d is not running anymore%s\n", colors.Yellow, pid, colors.Reset)
+			return
+		}
+
+		if !stopRecord(record, getStopGracePeriod()) {
+			os.Exit(1)
+		}
+
+		forgetProcess(pid)
+		fmt.Printf("%s✓ Stopped %d%s\n", colors.Green, pid, colors.Reset)
+		return
+	}
+
+	fmt.Printf("%sError: Process %d was not started by marn%s\n", colors.Red, pid, colors.Reset)
+	os.Exit(1)
+}
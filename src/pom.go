@@ -1,6 +1,7 @@
 package main
 
 import (
+    "bytes"
     "encoding/xml"
     "fmt"
     "os"
@@ -12,19 +13,29 @@ import (
 // POM represents the Maven pom.xml structure
 type POM struct {
     XMLName      xml.Name   `xml:"project"`
+    GroupID      string     `xml:"groupId"`
     ArtifactID   string     `xml:"artifactId"`
+    Version      string     `xml:"version"`
+    Packaging    string     `xml:"packaging"`
+    Parent       Parent     `xml:"parent"`
     Properties   Properties `xml:"properties"`
     Dependencies struct {
         Dependency []Dependency `xml:"dependency"`
     } `xml:"dependencies"`
+    DependencyManagement struct {
+        Dependencies struct {
+            Dependency []Dependency `xml:"dependency"`
+        } `xml:"dependencies"`
+    } `xml:"dependencyManagement"`
     Build struct {
         Plugins struct {
-            Plugin []struct {
-                Configuration struct {
-                    MainClass string `xml:"mainClass"`
-                } `xml:"configuration"`
-            } `xml:"plugin"`
+            Plugin []Plugin `xml:"plugin"`
         } `xml:"plugins"`
+        PluginManagement struct {
+            Plugins struct {
+                Plugin []Plugin `xml:"plugin"`
+            } `xml:"plugins"`
+        } `xml:"pluginManagement"`
     } `xml:"build"`
 }
 
@@ -33,11 +44,132 @@ type Properties struct {
     Raw []byte `xml:",innerxml"`
 }
 
+// Parent represents the parent of a Maven project
+type Parent struct {
+    GroupID      string  `xml:"groupId"`
+    ArtifactID   string  `xml:"artifactId"`
+    Version      string  `xml:"version"`
+    RelativePath *string `xml:"relativePath"`
+}
+
 // Dependency represents a Maven dependency
 type Dependency struct {
     GroupID    string `xml:"groupId"`
     ArtifactID string `xml:"artifactId"`
     Version    string `xml:"version"`
+    Scope      string `xml:"scope"`
+}
+
+// Plugin represents a Maven build plugin
+type Plugin struct {
+    GroupID       string `xml:"groupId"`
+    ArtifactID    string `xml:"artifactId"`
+    Version       string `xml:"version"`
+    Configuration struct {
+        MainClass string `xml:"mainClass"`
+    } `xml:"configuration"`
+}
+
+// defaultPluginGroupID is the group of plugins declared without one
+const defaultPluginGroupID = "org.apache.maven.plugins"
+
+// VersionProperty returns the property the version comes from, like guava.version for ${guava.version}
+// Returns "" when the version is not a single property
+func (d Dependency) VersionProperty() string {
+    return versionProperty(d.Version)
+}
+
+// Values returns the properties by name
+func (p Properties) Values() map[string]string {
+    values := make(map[string]string)
+    decoder := xml.NewDecoder(bytes.NewReader(p.Raw))
+
+    var name string
+    var text strings.Builder
+    depth := 0
+
+    for {
+        token, err := decoder.Token()
+        if err != nil {
+            return values
+        }
+
+        switch t := token.(type) {
+        case xml.StartElement:
+            depth++
+
+            if depth == 1 {
+                name = t.Name.Local
+                text.Reset()
+            }
+
+        case xml.CharData:
+
+            if depth == 1 {
+                text.Write(t)
+            }
+
+        case xml.EndElement:
+
+            if depth == 1 {
+                values[name] = strings.TrimSpace(text.String())
+            }
+
+            depth--
+        }
+    }
+}
+
+// Names returns the property names in the order they appear
+func (p Properties) Names() []string {
+    var names []string
+    decoder := xml.NewDecoder(bytes.NewReader(p.Raw))
+    depth := 0
+
+    for {
+        token, err := decoder.Token()
+        if err != nil {
+            return names
+        }
+
+        switch t := token.(type) {
+        case xml.StartElement:
+            depth++
+
+            if depth == 1 {
+                names = append(names, t.Name.Local)
+            }
+
+        case xml.EndElement:
+            depth--
+        }
+    }
+}
+
+// expandProperties replaces ${name} references with property values
+// Unknown properties are left as they are
+func expandProperties(value string, properties map[string]string) string {
+    re := regexp.MustCompile(`\$\{([^}]+)\}`)
+
+    // Properties may refer to other properties
+    for i := 0; i < 10 && strings.Contains(value, "${"); i++ {
+        expanded := re.ReplaceAllStringFunc(value, func(ref string) string {
+
+            if v, ok := properties[ref[2:len(ref)-1]]; ok {
+                return v
+            }
+
+            return ref
+        })
+
+        if expanded == value {
+            break
+        }
+
+        value = expanded
+    }
+
+    return value
 }
 
 // getScriptsFromPom extracts scripts from pom.xml properties
@@ -81,6 +213,29 @@ func getProperty(propName string) string {
     return ""
 }
 
+// hasPropertyPrefix checks if pom.xml defines any property starting with prefix
+func hasPropertyPrefix(prefix string) bool {
+    content, err := os.ReadFile(pomFile)
+    if err != nil {
+        return false
+    }
+
+    var pom POM
+    if err := xml.Unmarshal(content, &pom); err != nil {
+        return false
+    }
+
+    // Only real properties count, not comments or other elements
+    for name := range pom.Properties.Values() {
+
+        if strings.HasPrefix(name, prefix) {
+            return true
+        }
+    }
+
+    return false
+}
+
 // getArtifactID gets the artifact ID from pom.xml
 func getArtifactID() string {
     content, err := os.ReadFile(pomFile)
@@ -156,3 +311,98 @@ func getLocalDependencies() []string {
 
     return uniqueDeps
 }
+
+// getParentPoms returns the pom.xml files of the local parent chain
+// Parents are looked up like Maven does, through relativePath (default ../pom.xml)
+func getParentPoms() []string {
+    var parents []string
+    seen := map[string]bool{pomFile: true}
+    current := pomFile
+
+    for {
+        content, err := os.ReadFile(current)
+        if err != nil {
+            return parents
+        }
+
+        var pom POM
+        if err := xml.Unmarshal(content, &pom); err != nil || pom.Parent.ArtifactID == "" {
+            return parents
+        }
+
+        relativePath := "../pom.xml"
+        if pom.Parent.RelativePath != nil {
+            relativePath = strings.TrimSpace(*pom.Parent.RelativePath)
+        }
+
+        // An empty relativePath means the parent is only resolved from repositories
+        if relativePath == "" {
+            return parents
+        }
+
+        parentPom := filepath.Join(filepath.Dir(current), relativePath)
+        if info, err := os.Stat(parentPom); err == nil && info.IsDir() {
+            parentPom = filepath.Join(parentPom, "pom.xml")
+        }
+
+        parentPom, _ = filepath.Abs(parentPom)
+
+        // The file must be the declared parent
+        if seen[parentPom] || getPomArtifactID(parentPom) != pom.Parent.ArtifactID {
+            return parents
+        }
+
+        seen[parentPom] = true
+        parents = append(parents, parentPom)
+        current = parentPom
+    }
+}
+
+// getPomArtifactID gets the artifact ID of any pom.xml file
+func getPomArtifactID(path string) string {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return ""
+    }
+
+    var pom POM
+    if err := xml.Unmarshal(content, &pom); err != nil {
+        return ""
+    }
+
+    return pom.ArtifactID
+}
+
+// getDependencyFingerprint returns the sections of pom.xml and its parents that define dependencies
+// Formatting is ignored, so only real changes to the dependencies change the result
+// Properties are expanded, so changing a version property changes the result too
+func getDependencyFingerprint() string {
+    comments := regexp.MustCompile(`(?s)<!--.*?-->`)
+
+    var sections []string
+
+    for _, path := range append([]string{pomFile}, getParentPoms()...) {
+        content, err := os.ReadFile(path)
+        if err != nil {
+            continue
+        }
+
+        text := comments.ReplaceAllString(string(content), "")
+
+        for _, tag := range []string{"parent", "dependencyManagement", "dependencies"} {
+            re := regexp.MustCompile(fmt.Sprintf(`(?s)<%s>.*?</%s>`, tag, tag))
+
+            for _, section := range re.FindAllString(text, -1) {
+                sections = append(sections, strings.Join(strings.Fields(section), " "))
+            }
+        }
+    }
+
+    fingerprint := strings.Join(sections, "\n")
+
+    if poms, err := readProjectPoms(); err == nil {
+        fingerprint = expandProperties(fingerprint, getPomProperties(poms))
+    }
+
+    return fingerprint
+}
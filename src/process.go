@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// AppProcess is an application process started and owned by marn
+type AppProcess struct {
+	Command string
+	cmd     *exec.Cmd
+	done    chan struct{}
+	output  sync.WaitGroup
+}
+
+// ManagedApp keeps one instance of an application command running
+// Watch mode uses it to restart the application after each successful rebuild
+type ManagedApp struct {
+	Command     string
+	GracePeriod time.Duration
+	current     *AppProcess
+}
+
+// startAppProcess starts a shell command in its own process group
+// Its output is streamed to the terminal with an [app] prefix
+func startAppProcess(command string) (*AppProcess, error) {
+	expandedCommand := expandEnvVars(command)
+
+	fmt.Printf("%s$ %s%s\n", colors.Blue, expandedCommand, colors.Reset)
+
+	cmd := newShellCommand(expandedCommand)
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	app := &AppProcess{
+		Command: command,
+		cmd:     cmd,
+		done:    make(chan struct{}),
+	}
+
+	recordProcess(cmd.Process.Pid, "watch", true, []string{cmd.Args[0], expandedCommand})
+
+	app.output.Add(2)
+	go app.streamOutput(stdout)
+	go app.streamOutput(stderr)
+
+	go func() {
+		// Drain the output before Wait closes the pipes
+		app.output.Wait()
+		err := cmd.Wait()
+		forgetProcess(cmd.Process.Pid)
+
+		if err != nil {
+			fmt.Printf("%s[app] exited: %v%s\n", colors.Yellow, err, colors.Reset)
+		} else {
+			fmt.Printf("%s[app] exited%s\n", colors.Yellow, colors.Reset)
+		}
+
+		close(app.done)
+	}()
+
+	return app, nil
+}
+
+// streamOutput prints each line of the application output with a prefix
+func (a *AppProcess) streamOutput(r io.Reader) {
+	defer a.output.Done()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		fmt.Printf("%s[app]%s %s\n", colors.Blue, colors.Reset, scanner.Text())
+	}
+}
+
+// Running checks if the process hasn't exited yet
+func (a *AppProcess) Running() bool {
+	select {
+	case <-a.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stop asks the process to terminate and kills it after the grace period
+func (a *AppProcess) Stop(grace time.Duration) {
+	if !a.Running() {
+		return
+	}
+
+	fmt.Printf("%sStopping application (pid %d)...%s\n", colors.Yellow, a.cmd.Process.Pid, colors.Reset)
+
+	if err := terminatePid(a.cmd.Process.Pid, true); err != nil {
+		killPid(a.cmd.Process.Pid, true)
+	}
+
+	select {
+	case <-a.done:
+		return
+	case <-time.After(grace):
+	}
+
+	fmt.Printf("%sApplication did not stop within %v, killing it%s\n", colors.Yellow, grace, colors.Reset)
+	killPid(a.cmd.Process.Pid, true)
+
+	<-a.done
+}
+
+// Restart stops the running instance and starts a new one
+func (m *ManagedApp) Restart() {
+	m.Stop()
+
+	fmt.Printf("%sStarting application...%s\n", colors.Yellow, colors.Reset)
+
+	app, err := startAppProcess(m.Command)
+	if err != nil {
+		fmt.Printf("%s✗ Could not start application: %v%s\n", colors.Red, err, colors.Reset)
+		return
+	}
+
+	m.current = app
+}
+
+// Running checks if an instance is currently running
+func (m *ManagedApp) Running() bool {
+	return m.current != nil && m.current.Running()
+}
+
+// Stop stops the running instance if any
+func (m *ManagedApp) Stop() {
+	if m.current == nil {
+		return
+	}
+
+	m.current.Stop(m.GracePeriod)
+	m.current = nil
+}
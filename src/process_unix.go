@@ -0,0 +1,122 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in a new process group
+// Signals then reach the shell and everything it started
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminatePid sends SIGTERM to a process, or to its whole process group
+func terminatePid(pid int, group bool) error {
+	if group {
+		pid = -pid
+	}
+
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// killPid sends SIGKILL to a process, or to its whole process group
+func killPid(pid int, group bool) error {
+	if group {
+		pid = -pid
+	}
+
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
+
+// processExists checks if a process with the PID is running
+// A zombie waiting to be reaped by its parent has already exited
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+
+	// Linux shows the state after the command name in /proc, Z for zombies
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil {
+
+		if i := strings.LastIndexByte(string(data), ')'); i >= 0 && strings.HasPrefix(string(data[i+1:]), " Z") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// clockTicks is the unit of the start time in /proc/<pid>/stat, USER_HZ is 100 on every Linux
+const clockTicks = 100
+
+// getProcessStartTime returns when a running process was started
+func getProcessStartTime(pid int) (time.Time, error) {
+	// Linux counts the start time in clock ticks since boot
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil {
+		return parseProcStartTime(string(data))
+	}
+
+	output, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(strings.Fields(string(output)), " "), time.Local)
+}
+
+// parseProcStartTime reads the start time from the contents of /proc/<pid>/stat
+func parseProcStartTime(stat string) (time.Time, error) {
+	// The command name may contain spaces, the fields after it start with the state
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("unexpected /proc stat format")
+	}
+
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("unexpected /proc stat format")
+	}
+
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	bootTime, err := getBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return bootTime.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+// getBootTime reads the boot time from /proc/stat
+func getBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			return time.Unix(seconds, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("no boot time in /proc/stat")
+}
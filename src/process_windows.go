@@ -0,0 +1,66 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminatePid asks a process, or its whole process tree, to close
+func terminatePid(pid int, group bool) error {
+	args := []string{"/PID", strconv.Itoa(pid)}
+	if group {
+		args = append(args, "/T")
+	}
+
+	return exec.Command("taskkill", args...).Run()
+}
+
+// killPid forcefully kills a process, or its whole process tree
+func killPid(pid int, group bool) error {
+	args := []string{"/F", "/PID", strconv.Itoa(pid)}
+	if group {
+		args = append(args, "/T")
+	}
+
+	return exec.Command("taskkill", args...).Run()
+}
+
+// processExists checks if a process with the PID is running
+func processExists(pid int) bool {
+	output, err := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(output), fmt.Sprintf("\"%d\"", pid))
+}
+
+// processQueryLimitedInformation is enough access to read the times of any process
+const processQueryLimitedInformation = 0x1000
+
+// getProcessStartTime returns when a running process was created
+func getProcessStartTime(pid int) (time.Time, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultRepositoryURL is used to look up versions when marn.repository is not set
+const defaultRepositoryURL = "https://repo.maven.apache.org/maven2"
+
+// mavenMetadata is a maven-metadata.xml file of an artifact
+type mavenMetadata struct {
+	Versioning struct {
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+// getLocalRepository returns the local Maven repository
+// The localRepository of ~/.m2/settings.xml wins over ~/.m2/repository
+func getLocalRepository() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	content, err := os.ReadFile(filepath.Join(homeDir, ".m2", "settings.xml"))
+	if err == nil {
+		// The default settings.xml has an example in a comment
+		content = regexp.MustCompile(`(?s)<!--.*?-->`).ReplaceAll(content, nil)
+		re := regexp.MustCompile(`<localRepository>([^<]+)</localRepository>`)
+
+		if match := re.FindSubmatch(content); match != nil {
+			return strings.TrimSpace(string(match[1]))
+		}
+	}
+
+	return filepath.Join(homeDir, ".m2", "repository")
+}
+
+// getRepositoryURL returns the remote repository versions are looked up in
+// Returns "" when Maven runs offline
+func getRepositoryURL() string {
+	for _, arg := range getMavenArgs() {
+
+		if arg == "-o" || arg == "--offline" {
+			return ""
+		}
+	}
+
+	if url := getProperty("marn.repository"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+
+	if url := os.Getenv("MARN_REPOSITORY"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+
+	return defaultRepositoryURL
+}
+
+// artifactPath returns the repository path of an artifact, like com/google/guava/guava
+func artifactPath(groupID, artifactID string) string {
+	return strings.ReplaceAll(groupID, ".", "/") + "/" + artifactID
+}
+
+// repositoryPomPath returns the path of the pom of an artifact version in the local repository
+func repositoryPomPath(groupID, artifactID, version string) string {
+	return filepath.Join(getLocalRepository(), filepath.FromSlash(artifactPath(groupID, artifactID)), version, artifactID+"-"+version+".pom")
+}
+
+// getAvailableVersions returns the versions of an artifact in the local and the remote repository
+// The remote repository is optional, its error is only returned when no version was found
+func getAvailableVersions(groupID, artifactID string) ([]string, error) {
+	seen := make(map[string]bool)
+	var versions []string
+
+	add := func(list []string) {
+		for _, version := range list {
+
+			if version != "" && !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	add(readLocalVersions(groupID, artifactID))
+
+	var remoteErr error
+
+	if url := getRepositoryURL(); url != "" {
+		remote, err := fetchRemoteVersions(url, groupID, artifactID)
+		remoteErr = err
+		add(remote)
+	}
+
+	if len(versions) == 0 && remoteErr != nil {
+		return nil, remoteErr
+	}
+
+	return versions, nil
+}
+
+// readLocalVersions reads the versions of an artifact from the local repository
+func readLocalVersions(groupID, artifactID string) []string {
+	dir := filepath.Join(getLocalRepository(), filepath.FromSlash(artifactPath(groupID, artifactID)))
+
+	var versions []string
+
+	// Metadata of every repository the artifact was downloaded from
+	files, _ := filepath.Glob(filepath.Join(dir, "maven-metadata*.xml"))
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		var metadata mavenMetadata
+		if err := xml.Unmarshal(content, &metadata); err == nil {
+			versions = append(versions, metadata.Versioning.Versions...)
+		}
+	}
+
+	// Version directories with a pom, for artifacts installed without metadata
+	entries, _ := os.ReadDir(dir)
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		pom := filepath.Join(dir, entry.Name(), artifactID+"-"+entry.Name()+".pom")
+
+		if _, err := os.Stat(pom); err == nil {
+			versions = append(versions, entry.Name())
+		}
+	}
+
+	return versions
+}
+
+// fetchRemoteVersions reads maven-metadata.xml of an artifact from a repository
+// file:// URLs are read from disk
+func fetchRemoteVersions(url, groupID, artifactID string) ([]string, error) {
+	metadataURL := url + "/" + artifactPath(groupID, artifactID) + "/maven-metadata.xml"
+
+	var content []byte
+
+	if strings.HasPrefix(metadataURL, "file://") {
+		data, err := os.ReadFile(filepath.FromSlash(strings.TrimPrefix(metadataURL, "file://")))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		content = data
+	} else {
+		client := &http.Client{Timeout: 10 * time.Second}
+
+		resp, err := client.Get(metadataURL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned %s", metadataURL, resp.Status)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		content = data
+	}
+
+	var metadata mavenMetadata
+	if err := xml.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", metadataURL, err)
+	}
+
+	return metadata.Versioning.Versions, nil
+}
+
+// findLocalGroupIDs finds the groups of an artifact ID in the local repository
+func findLocalGroupIDs(artifactID string) []string {
+	root := getLocalRepository()
+	var groups []string
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == root {
+			return nil
+		}
+
+		// Version directories never contain artifacts
+		if name := d.Name(); name != "" && name[0] >= '0' && name[0] <= '9' {
+			return filepath.SkipDir
+		}
+
+		if d.Name() != artifactID {
+			return nil
+		}
+
+		if len(readLocalVersions(groupFromPath(root, filepath.Dir(path)), artifactID)) > 0 {
+			groups = append(groups, groupFromPath(root, filepath.Dir(path)))
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	sort.Strings(groups)
+	return groups
+}
+
+// groupFromPath turns a directory of the local repository into a group ID
+func groupFromPath(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")
+}
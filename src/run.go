@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RunOptions holds the options passed to 'marn run'
+type RunOptions struct {
+	Exec      bool
+	Clean     bool
+	MainClass string
+	Config    string
+	Debug     DebugOptions
+	AppArgs   []string
+}
+
+// RunConfig holds how 'marn run' launches java
+// Defaults come from marn.run.* properties, named configurations from marn.run.<name>.*
+type RunConfig struct {
+	Name      string
+	JvmArgs   []string
+	Args      []string
+	JavaHome  string
+	Toolchain string
+	Workdir   string
+	Env       []string
+}
+
+// Toolchains represents the ~/.m2/toolchains.xml structure
+type Toolchains struct {
+	Toolchain []struct {
+		Type     string `xml:"type"`
+		Provides struct {
+			Version string `xml:"version"`
+			Vendor  string `xml:"vendor"`
+		} `xml:"provides"`
+		Configuration struct {
+			JdkHome string `xml:"jdkHome"`
+		} `xml:"configuration"`
+	} `xml:"toolchain"`
+}
+
+// ClasspathCache stores the resolved runtime classpath for a project
+type ClasspathCache struct {
+	PomHash   string `json:"pomHash"`
+	Classpath string `json:"classpath"`
+}
+
+// parseRunArgs parses the arguments given to 'marn run'
+// Flags that marn doesn't know are passed through to the application
+func parseRunArgs(args []string) (RunOptions, error) {
+	var opts RunOptions
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			// Everything after -- belongs to the application
+			opts.AppArgs = append(opts.AppArgs, args[i+1:]...)
+			return opts, nil
+
+		case arg == "--exec":
+			opts.Exec = true
+
+		case arg == "--clean":
+			opts.Clean = true
+
+		case arg == "--main" && i+1 < len(args):
+			opts.Exec = true
+			opts.MainClass = args[i+1]
+			i++
+
+		case strings.HasPrefix(arg, "--main="):
+			opts.Exec = true
+			opts.MainClass = strings.TrimPrefix(arg, "--main=")
+
+		case arg == "--config" && i+1 < len(args):
+			opts.Config = args[i+1]
+			i++
+
+		case strings.HasPrefix(arg, "--config="):
+			opts.Config = strings.TrimPrefix(arg, "--config=")
+
+		case arg == "--debug" || strings.HasPrefix(arg, "--debug="):
+			debug, err := parseDebugSpec(strings.TrimPrefix(strings.TrimPrefix(arg, "--debug"), "="))
+			if err != nil {
+				return opts, err
+			}
+
+			opts.Debug = debug
+
+		default:
+			opts.AppArgs = append(opts.AppArgs, arg)
+		}
+	}
+
+	// Inherit debug settings from watch mode
+	if !opts.Debug.Enabled {
+		debug, err := getDebugFromEnv()
+		if err != nil {
+			return opts, err
+		}
+
+		opts.Debug = debug
+	}
+
+	return opts, nil
+}
+
+// getClasspathCachePath returns the path to the classpath cache file
+func getClasspathCachePath(projectPath string) string {
+	return filepath.Join(projectPath, ".marn", "classpath.json")
+}
+
+// resolveClasspath returns the runtime classpath of the project
+// The result of dependency:build-classpath is cached until pom.xml, its parents or the Maven arguments change
+func resolveClasspath() (string, error) {
+	pomHash, err := calculatePomHash()
+	if err != nil {
+		return "", err
+	}
+
+	cachePath := getClasspathCachePath(currentDir)
+
+	// Use the cached classpath if nothing changed
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var cache ClasspathCache
+
+		if err := json.Unmarshal(data, &cache); err == nil && cache.PomHash == pomHash {
+			return cache.Classpath, nil
+		}
+	}
+
+	fmt.Fprintf(console, "%sResolving runtime classpath...%s\n", colors.Green, colors.Reset)
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return "", err
+	}
+
+	outputFile := filepath.Join(currentDir, ".marn", "classpath.txt")
+	defer os.Remove(outputFile)
+
+	if err := runMvnCommand("-q", "dependency:build-classpath", "-Dmdep.includeScope=runtime", "-Dmdep.outputFile="+outputFile); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		return "", err
+	}
+
+	cache := ClasspathCache{
+		PomHash:   pomHash,
+		Classpath: strings.TrimSpace(string(content)),
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return "", err
+	}
+
+	return cache.Classpath, nil
+}
+
+// findMainClasses finds all classes declaring a main method in src/main/java
+func findMainClasses() []string {
+	srcDir := filepath.Join(currentDir, "src", "main", "java")
+	mainRe := regexp.MustCompile(`static\s+void\s+main\s*\(`)
+	packageRe := regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+
+	var classes []string
+
+	filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".java") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || !mainRe.Match(content) {
+			return nil
+		}
+
+		className := strings.TrimSuffix(filepath.Base(path), ".java")
+
+		if match := packageRe.FindSubmatch(content); match != nil {
+			className = string(match[1]) + "." + className
+		}
+
+		classes = append(classes, className)
+		return nil
+	})
+
+	sort.Strings(classes)
+	return classes
+}
+
+// resolveMainClass determines which class 'marn run --exec' should start
+// Uses --main if given, then the mainClass property, then the only entry point found
+func resolveMainClass(opts RunOptions) (string, error) {
+	if opts.MainClass != "" {
+		return opts.MainClass, nil
+	}
+
+	if mainClass := getMainClass(); mainClass != "" {
+		return mainClass, nil
+	}
+
+	candidates := findMainClasses()
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no main class found, set <mainClass> in pom.xml or use --main")
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("multiple main classes found, choose one with --main:\n  %s", strings.Join(candidates, "\n  "))
+	}
+}
+
+// buildExecClasspath returns the classpath used to run target/classes directly
+func buildExecClasspath() (string, error) {
+	dependencies, err := resolveClasspath()
+	if err != nil {
+		return "", err
+	}
+
+	classpath := filepath.Join(currentDir, "target", "classes")
+	if dependencies != "" {
+		classpath += string(os.PathListSeparator) + dependencies
+	}
+
+	return classpath, nil
+}
+
+// loadRunConfig loads the run configuration from pom.xml
+// A named configuration adds its jvmArgs and env to the defaults and overrides the other settings
+func loadRunConfig(name string) (RunConfig, error) {
+	config := RunConfig{
+		Name:      name,
+		JvmArgs:   splitArgs(getProperty("marn.run.jvmArgs")),
+		Args:      splitArgs(getProperty("marn.run.args")),
+		JavaHome:  getProperty("marn.run.javaHome"),
+		Toolchain: getProperty("marn.run.toolchain"),
+		Workdir:   getProperty("marn.run.workdir"),
+		Env:       splitArgs(getProperty("marn.run.env")),
+	}
+
+	if name == "" {
+		return config, nil
+	}
+
+	prefix := "marn.run." + name + "."
+	if !hasPropertyPrefix(prefix) {
+		return config, fmt.Errorf("run configuration '%s' not found in pom.xml", name)
+	}
+
+	config.JvmArgs = append(config.JvmArgs, splitArgs(getProperty(prefix+"jvmArgs"))...)
+	config.Env = append(config.Env, splitArgs(getProperty(prefix+"env"))...)
+
+	if args := getProperty(prefix + "args"); args != "" {
+		config.Args = splitArgs(args)
+	}
+
+	if javaHome := getProperty(prefix + "javaHome"); javaHome != "" {
+		config.JavaHome = javaHome
+		config.Toolchain = ""
+	}
+
+	if toolchain := getProperty(prefix + "toolchain"); toolchain != "" {
+		config.Toolchain = toolchain
+		config.JavaHome = ""
+	}
+
+	if workdir := getProperty(prefix + "workdir"); workdir != "" {
+		config.Workdir = workdir
+	}
+
+	return config, nil
+}
+
+// getJavaCommand returns the java executable for a run configuration
+// Falls back to java on PATH when no javaHome or toolchain is configured
+func getJavaCommand(config RunConfig) (string, error) {
+	javaHome := expandEnvVars(config.JavaHome)
+
+	if config.Toolchain != "" {
+		home, err := findToolchainJdk(config.Toolchain)
+		if err != nil {
+			return "", err
+		}
+
+		javaHome = home
+	}
+
+	if javaHome == "" {
+		return "java", nil
+	}
+
+	javaCmd := filepath.Join(javaHome, "bin", "java")
+	if isWindows() {
+		javaCmd += ".exe"
+	}
+
+	if _, err := os.Stat(javaCmd); err != nil {
+		return "", fmt.Errorf("java not found in %s", javaHome)
+	}
+
+	return javaCmd, nil
+}
+
+// findToolchainJdk finds the JDK home for a version in ~/.m2/toolchains.xml
+// The version may be followed by a vendor, e.g. "17" or "21:temurin"
+func findToolchainJdk(toolchain string) (string, error) {
+	version, vendor, _ := strings.Cut(toolchain, ":")
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(filepath.Join(homeDir, ".m2", "toolchains.xml"))
+	if err != nil {
+		return "", fmt.Errorf("could not read ~/.m2/toolchains.xml: %v", err)
+	}
+
+	var toolchains Toolchains
+	if err := xml.Unmarshal(content, &toolchains); err != nil {
+		return "", fmt.Errorf("could not parse ~/.m2/toolchains.xml: %v", err)
+	}
+
+	for _, tc := range toolchains.Toolchain {
+		if tc.Type != "jdk" || tc.Configuration.JdkHome == "" {
+			continue
+		}
+
+		if tc.Provides.Version != version && !strings.HasPrefix(tc.Provides.Version, version+".") {
+			continue
+		}
+
+		if vendor != "" && !strings.EqualFold(tc.Provides.Vendor, vendor) {
+			continue
+		}
+
+		return tc.Configuration.JdkHome, nil
+	}
+
+	return "", fmt.Errorf("no JDK toolchain found for version %s", toolchain)
+}
+
+// getRunWorkdir returns the absolute working directory for a run configuration
+func getRunWorkdir(config RunConfig) string {
+	workdir := expandEnvVars(config.Workdir)
+
+	if workdir == "" {
+		return currentDir
+	}
+
+	if !filepath.IsAbs(workdir) {
+		workdir = filepath.Join(currentDir, workdir)
+	}
+
+	return workdir
+}
+
+// buildJavaCommand creates the java command for a run configuration
+// target is either "-jar <file>" or "-cp <classpath> <mainClass>"
+func buildJavaCommand(config RunConfig, target []string, appArgs []string) (*exec.Cmd, error) {
+	javaCmd, err := getJavaCommand(config)
+	if err != nil {
+		return nil, err
+	}
+
+	workdir := getRunWorkdir(config)
+	if err := os.MkdirAll(workdir, 0755); err != nil {
+		return nil, err
+	}
+
+	var args []string
+
+	for _, arg := range config.JvmArgs {
+		args = append(args, expandEnvVars(arg))
+	}
+
+	args = append(args, target...)
+
+	// Arguments given on the command line replace the configured ones
+	if len(appArgs) > 0 {
+		args = append(args, appArgs...)
+	} else {
+		for _, arg := range config.Args {
+			args = append(args, expandEnvVars(arg))
+		}
+	}
+
+	cmd := exec.Command(javaCmd, args...)
+	cmd.Dir = workdir
+	cmd.Env = os.Environ()
+
+	for _, env := range config.Env {
+		cmd.Env = append(cmd.Env, expandEnvVars(env))
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,11 @@
+//go:build linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// ioctl requests to read and write the terminal settings
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
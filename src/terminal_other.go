@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// isTerminal reports no terminal, key input is not supported on this platform
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+// enableKeyInput is not supported on this platform
+func enableKeyInput(f *os.File) (func(), error) {
+	return nil, fmt.Errorf("key input is not supported on this platform")
+}
+
+// waitForInput is not supported on this platform
+func waitForInput(f *os.File, timeout time.Duration) (bool, error) {
+	return false, fmt.Errorf("key input is not supported on this platform")
+}
@@ -0,0 +1,57 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTerminal checks if a file is a terminal
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), ioctlGetTermios)
+	return err == nil
+}
+
+// enableKeyInput switches the terminal to read single key presses without echo
+// Ctrl+C keeps working, the returned function restores the previous settings
+func enableKeyInput(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		unix.IoctlSetTermios(fd, ioctlSetTermios, old)
+	}, nil
+}
+
+// waitForInput waits until a file has input to read
+// Returns false when nothing arrived within the timeout
+func waitForInput(f *os.File, timeout time.Duration) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(f.Fd()), Events: unix.POLLIN}}
+
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err == unix.EINTR {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Console input modes
+const (
+	enableLineInput = 0x0002
+	enableEchoInput = 0x0004
+)
+
+// isTerminal checks if a file is a console
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	ok, _, _ := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode)))
+	return ok != 0
+}
+
+// enableKeyInput switches the console to read single key presses without echo
+// Ctrl+C keeps working, the returned function restores the previous mode
+func enableKeyInput(f *os.File) (func(), error) {
+	var old uint32
+	if ok, _, err := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&old))); ok == 0 {
+		return nil, fmt.Errorf("GetConsoleMode: %v", err)
+	}
+
+	raw := old &^ (enableLineInput | enableEchoInput)
+
+	if ok, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(raw)); ok == 0 {
+		return nil, fmt.Errorf("SetConsoleMode: %v", err)
+	}
+
+	return func() {
+		procSetConsoleMode.Call(f.Fd(), uintptr(old))
+	}, nil
+}
+
+// waitForInput waits until the console has input to read
+// Returns false when nothing arrived within the timeout
+func waitForInput(f *os.File, timeout time.Duration) (bool, error) {
+	event, err := syscall.WaitForSingleObject(syscall.Handle(f.Fd()), uint32(timeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+
+	return event != syscall.WAIT_TIMEOUT, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TestSummary holds the results of a test run read from the surefire reports
+type TestSummary struct {
+	Tests    int
+	Failures int
+	Errors   int
+	Skipped  int
+	Failed   []TestFailure
+}
+
+// TestFailure is a failed or erroneous test case
+type TestFailure struct {
+	Name      string
+	ClassName string
+	Message   string
+	Trace     string
+}
+
+// surefireSuite is a TEST-*.xml report written by surefire
+type surefireSuite struct {
+	XMLName   xml.Name `xml:"testsuite"`
+	Tests     int      `xml:"tests,attr"`
+	Failures  int      `xml:"failures,attr"`
+	Errors    int      `xml:"errors,attr"`
+	Skipped   int      `xml:"skipped,attr"`
+	TestCases []struct {
+		Name      string `xml:"name,attr"`
+		ClassName string `xml:"classname,attr"`
+		Failure   *struct {
+			Message string `xml:"message,attr"`
+			Trace   string `xml:",chardata"`
+		} `xml:"failure"`
+		Error *struct {
+			Message string `xml:"message,attr"`
+			Trace   string `xml:",chardata"`
+		} `xml:"error"`
+	} `xml:"testcase"`
+}
+
+// runWatchTests runs the tests for a batch of changes in watch mode
+// In affected mode only the tests related to the changed classes run
+func runWatchTests(ctx context.Context, mode string, changes map[string]fsnotify.Op) bool {
+	goals := "test-compile surefire:test"
+
+	if mode == "affected" {
+		tests := buildClassIndex().AffectedTests(changedClasses(changes))
+
+		if len(tests) == 0 {
+			fmt.Printf("%sNo affected tests%s\n", colors.Green, colors.Reset)
+			return true
+		}
+
+		fmt.Printf("%sRunning %d affected test class(es)...%s\n", colors.Green, len(tests), colors.Reset)
+		goals += " -Dtest=" + strings.Join(tests, ",") + " -Dsurefire.failIfNoSpecifiedTests=false"
+	} else {
+		fmt.Printf("%sRunning tests...%s\n", colors.Green, colors.Reset)
+	}
+
+	start := time.Now()
+
+	success, _ := runMvnBuild(ctx, goals, false)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	summary := readSurefireReports(start)
+
+	if summary.Tests == 0 {
+
+		if !success {
+			fmt.Printf("%s✗ Tests could not be run%s\n", colors.Red, colors.Reset)
+			return false
+		}
+
+		fmt.Printf("%sNo tests were run%s\n", colors.Yellow, colors.Reset)
+		return true
+	}
+
+	printTestSummary(summary, time.Since(start))
+	return success
+}
+
+// readSurefireReports sums up the surefire reports written since a point in time
+func readSurefireReports(since time.Time) TestSummary {
+	var summary TestSummary
+
+	reports, _ := filepath.Glob(filepath.Join(currentDir, "target", "surefire-reports", "TEST-*.xml"))
+
+	for _, report := range reports {
+		info, err := os.Stat(report)
+		if err != nil || info.ModTime().Before(since.Truncate(time.Second)) {
+			continue
+		}
+
+		content, err := os.ReadFile(report)
+		if err != nil {
+			continue
+		}
+
+		var suite surefireSuite
+		if err := xml.Unmarshal(content, &suite); err != nil {
+			continue
+		}
+
+		summary.Tests += suite.Tests
+		summary.Failures += suite.Failures
+		summary.Errors += suite.Errors
+		summary.Skipped += suite.Skipped
+
+		for _, testCase := range suite.TestCases {
+			name := testCase.ClassName + "." + testCase.Name
+
+			if testCase.Failure != nil {
+				summary.Failed = append(summary.Failed, TestFailure{Name: name, ClassName: testCase.ClassName, Message: testCase.Failure.Message, Trace: testCase.Failure.Trace})
+			} else if testCase.Error != nil {
+				summary.Failed = append(summary.Failed, TestFailure{Name: name, ClassName: testCase.ClassName, Message: testCase.Error.Message, Trace: testCase.Error.Trace})
+			}
+		}
+	}
+
+	return summary
+}
+
+// printTestSummary prints a compact pass/fail summary
+func printTestSummary(summary TestSummary, elapsed time.Duration) {
+	failed := summary.Failures + summary.Errors
+	passed := summary.Tests - failed - summary.Skipped
+
+	counts := fmt.Sprintf("%d passed, %d failed", passed, failed)
+	if summary.Skipped > 0 {
+		counts += ", " + strconv.Itoa(summary.Skipped) + " skipped"
+	}
+
+	if failed == 0 {
+		fmt.Printf("%s✓ Tests: %s (%.1fs)%s\n", colors.Green, counts, elapsed.Seconds(), colors.Reset)
+		return
+	}
+
+	fmt.Printf("%s✗ Tests: %s (%.1fs)%s\n", colors.Red, counts, elapsed.Seconds(), colors.Reset)
+
+	const maxShown = 10
+
+	for i, failure := range summary.Failed {
+
+		if i == maxShown {
+			fmt.Printf("  ... and %d more\n", len(summary.Failed)-maxShown)
+			break
+		}
+
+		message := strings.TrimSpace(strings.SplitN(failure.Message, "\n", 2)[0])
+
+		if message != "" {
+			fmt.Printf("  %s✗%s %s: %s\n", colors.Red, colors.Reset, failure.Name, message)
+		} else {
+			fmt.Printf("  %s✗%s %s\n", colors.Red, colors.Reset, failure.Name)
+		}
+	}
+}
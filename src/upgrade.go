@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/machinastudios/marn/internal/pomedit"
+)
+
+// How far 'marn upgrade' moves versions
+const (
+	upgradePatch  = "patch"
+	upgradeMinor  = "minor"
+	upgradeLatest = "latest"
+)
+
+// UpgradeOptions holds the options passed to 'marn upgrade' and 'marn upgrade-interactive'
+type UpgradeOptions struct {
+	Target string
+	Specs  []DependencySpec
+}
+
+// Upgrade is a new version for a dependency or plugin
+type Upgrade struct {
+	Entry   *OutdatedEntry
+	Version string
+}
+
+// parseUpgradeArgs parses the arguments given to 'marn upgrade'
+func parseUpgradeArgs(args []string) (UpgradeOptions, error) {
+	opts := UpgradeOptions{Target: upgradeMinor}
+
+	for _, arg := range args {
+
+		switch {
+		case arg == "--patch":
+			opts.Target = upgradePatch
+
+		case arg == "--minor":
+			opts.Target = upgradeMinor
+
+		case arg == "--latest":
+			opts.Target = upgradeLatest
+
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown option '%s'", arg)
+
+		default:
+			spec, err := parseDependencySpec(arg)
+			if err != nil {
+				return opts, err
+			}
+
+			opts.Specs = append(opts.Specs, spec)
+		}
+	}
+
+	return opts, nil
+}
+
+// upgradeDependencies implements 'marn upgrade', moving dependencies and plugins to newer versions
+func upgradeDependencies() {
+	opts, err := parseUpgradeArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	poms, entries := loadUpgradeEntries(opts)
+	properties := getPomProperties(poms)
+
+	var upgrades []Upgrade
+
+	for _, entry := range entries {
+
+		if entry.Error != "" {
+			fmt.Printf("%sCould not check %s: %s%s\n", colors.Yellow, entry.Key(), entry.Error, colors.Reset)
+			continue
+		}
+
+		version := upgradeVersion(entry, opts.Target)
+
+		// A version given on the command line wins
+		for _, spec := range opts.Specs {
+
+			if spec.Version != "" && matchesSpec(entry, spec) {
+				version = spec.Version
+			}
+		}
+
+		if version == "" || version == entry.Current {
+			continue
+		}
+
+		if isVersionRange(expandProperties(entry.Declared, properties)) {
+			fmt.Printf("%sSkipping %s, its version is the range %s%s\n", colors.Yellow, entry.Key(), entry.Declared, colors.Reset)
+			continue
+		}
+
+		upgrades = append(upgrades, Upgrade{Entry: entry, Version: version})
+	}
+
+	if len(upgrades) == 0 {
+		fmt.Printf("%s✓ Everything is up to date%s\n", colors.Green, colors.Reset)
+		return
+	}
+
+	applyUpgrades(poms, upgrades)
+}
+
+// loadUpgradeEntries reads the project and looks up the versions of the entries the specs select
+// Exits when a spec matches no dependency or plugin
+func loadUpgradeEntries(opts UpgradeOptions) ([]projectPom, []*OutdatedEntry) {
+	poms, err := readProjectPoms()
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	all := collectOutdatedEntries(poms)
+	entries := all
+
+	if len(opts.Specs) > 0 {
+		entries = nil
+
+		for _, spec := range opts.Specs {
+			found := false
+
+			for _, entry := range all {
+
+				if matchesSpec(entry, spec) {
+					entries = append(entries, entry)
+					found = true
+				}
+			}
+
+			if !found {
+				fmt.Printf("%sError: %s is not a dependency or plugin of this project%s\n", colors.Red, DependencySpec{GroupID: spec.GroupID, ArtifactID: spec.ArtifactID}, colors.Reset)
+				os.Exit(1)
+			}
+		}
+	}
+
+	fmt.Printf("%sChecking %d artifacts...%s\n", colors.Blue, len(entries), colors.Reset)
+	checkOutdatedEntries(entries)
+
+	return poms, entries
+}
+
+// matchesSpec reports whether an entry is the artifact of a spec, the version is ignored
+func matchesSpec(entry *OutdatedEntry, spec DependencySpec) bool {
+	return entry.ArtifactID == spec.ArtifactID && (spec.GroupID == "" || entry.GroupID == spec.GroupID)
+}
+
+// upgradeVersion returns the version an entry moves to, or "" when there is none
+func upgradeVersion(entry *OutdatedEntry, target string) string {
+	switch target {
+	case upgradePatch:
+		return entry.LatestPatch
+	case upgradeLatest:
+		return entry.LatestMajor
+	default:
+		return entry.LatestMinor
+	}
+}
+
+// applyUpgrades writes the new versions to pom.xml, or its parents for managed versions and properties
+// The result is checked with 'mvn -q validate' and the files are restored when it fails
+func applyUpgrades(poms []projectPom, upgrades []Upgrade) {
+	docs := make(map[string]*pomedit.Document)
+	originals := make(map[string][]byte)
+	var loaded []string
+
+	load := func(path string) (*pomedit.Document, error) {
+		if doc, ok := docs[path]; ok {
+			return doc, nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := pomedit.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", displayPath(path), err)
+		}
+
+		docs[path] = doc
+		originals[path] = content
+		loaded = append(loaded, path)
+
+		return doc, nil
+	}
+
+	properties := getPomProperties(poms)
+
+	// Entries sharing a property must agree on its version
+	setProperties := make(map[string]string)
+	upgraded := 0
+
+	for _, upgrade := range upgrades {
+		entry := upgrade.Entry
+
+		path, element, err := findVersionElement(poms, entry, properties, load)
+		if err != nil {
+			fmt.Printf("%sSkipping %s: %v%s\n", colors.Yellow, entry.Key(), err, colors.Reset)
+			continue
+		}
+
+		location := ""
+		if entry.Property != "" {
+			location = "${" + entry.Property + "}"
+		}
+
+		if path != pomFile {
+			location = strings.TrimSpace(location + " in " + displayPath(path))
+		}
+
+		if location != "" {
+			location = " (" + location + ")"
+		}
+
+		if entry.Property != "" {
+			key := path + "#" + entry.Property
+
+			if version, ok := setProperties[key]; ok {
+
+				if version != upgrade.Version {
+					fmt.Printf("%sSkipping %s, ${%s} was already set to %s%s\n", colors.Yellow, entry.Key(), entry.Property, version, colors.Reset)
+				}
+
+				continue
+			}
+
+			setProperties[key] = upgrade.Version
+		}
+
+		element.SetText(upgrade.Version)
+		upgraded++
+		fmt.Printf("%s~ %s %s -> %s%s%s\n", colors.Yellow, entry.Key(), entry.Current, upgrade.Version, location, colors.Reset)
+	}
+
+	if upgraded == 0 {
+		return
+	}
+
+	// Unchanged documents are written back byte for byte
+	for _, path := range loaded {
+
+		if err := writePomFile(path, docs[path].Bytes()); err != nil {
+			fmt.Printf("%sError: Could not write %s: %v%s\n", colors.Red, displayPath(path), err, colors.Reset)
+			restorePoms(originals)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println()
+
+	if err := runMvnCommand("-q", "validate"); err != nil {
+		restorePoms(originals)
+		fmt.Printf("%sError: The upgraded pom.xml does not validate, the changes were reverted%s\n", colors.Red, colors.Reset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s✓ Upgraded %d artifacts, run 'marn install' to download them%s\n", colors.Green, upgraded, colors.Reset)
+}
+
+// findVersionElement finds the element that holds the version of an entry
+// That is the property the version comes from, the managing pom's entry, or the entry itself
+func findVersionElement(poms []projectPom, entry *OutdatedEntry, properties map[string]string, load func(string) (*pomedit.Document, error)) (string, *pomedit.Element, error) {
+	if entry.Property != "" {
+
+		// The nearest pom defining the property wins, like in Maven
+		for _, p := range poms {
+
+			// Poms from the local repository are not part of the project
+			if p.Artifact != "" {
+				continue
+			}
+
+			doc, err := load(p.Path)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if element := doc.First("project/properties/" + entry.Property); element != nil {
+				return p.Path, element, nil
+			}
+		}
+
+		// Overriding the property in the project is left to the user
+		if entry.external {
+			return "", nil, fmt.Errorf("property %s of %s is defined by %s outside the project", entry.Property, entry.Key(), entry.ManagedIn)
+		}
+
+		return "", nil, fmt.Errorf("property %s of %s is not defined in the project", entry.Property, entry.Key())
+	}
+
+	if entry.external {
+		return "", nil, fmt.Errorf("the version of %s is managed by %s outside the project", entry.Key(), entry.ManagedIn)
+	}
+
+	path := pomFile
+	var sections []string
+
+	switch {
+	case entry.Type == outdatedPlugin && entry.ManagedIn != "":
+		path = entry.managedPath
+		sections = []string{"project/build/pluginManagement/plugins/plugin"}
+
+	case entry.Type == outdatedPlugin:
+		sections = []string{"project/build/plugins/plugin", "project/build/pluginManagement/plugins/plugin"}
+
+	case entry.Type == outdatedManaged:
+		sections = []string{"project/dependencyManagement/dependencies/dependency"}
+
+	case entry.ManagedIn != "":
+		path = entry.managedPath
+		sections = []string{"project/dependencyManagement/dependencies/dependency"}
+
+	default:
+		sections = []string{"project/dependencies/dependency"}
+	}
+
+	doc, err := load(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, section := range sections {
+
+		for _, element := range doc.Find(section) {
+			artifactID := element.Child("artifactId")
+			version := element.Child("version")
+
+			if artifactID == nil || version == nil || artifactID.Text() != entry.ArtifactID {
+				continue
+			}
+
+			groupID := ""
+			if group := element.Child("groupId"); group != nil {
+				groupID = expandProperties(group.Text(), properties)
+			}
+
+			if groupID == "" && entry.Type == outdatedPlugin {
+				groupID = defaultPluginGroupID
+			}
+
+			if groupID == entry.GroupID {
+				return path, version, nil
+			}
+		}
+	}
+
+	return "", nil, fmt.Errorf("could not find the version of %s in %s", entry.Key(), displayPath(path))
+}
+
+// restorePoms writes back the original content of edited pom files
+func restorePoms(originals map[string][]byte) {
+	for path, content := range originals {
+
+		if err := writePomFile(path, content); err != nil {
+			fmt.Printf("%sError: Could not restore %s: %v%s\n", colors.Red, displayPath(path), err, colors.Reset)
+		}
+	}
+}
+
+// displayPath returns a path relative to the project when possible
+func displayPath(path string) string {
+	if rel, err := filepath.Rel(currentDir, path); err == nil {
+		return rel
+	}
+
+	return path
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// UpgradeChoice is a row of 'marn upgrade-interactive'
+type UpgradeChoice struct {
+	Entry    *OutdatedEntry
+	Versions []string // the distinct patch, minor and major updates
+	Selected int
+	Checked  bool
+}
+
+// upgradeInteractive implements 'marn upgrade-interactive', picking upgrades with checkboxes
+func upgradeInteractive() {
+	opts, err := parseUpgradeArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	if !isTerminal(os.Stdin) {
+		fmt.Printf("%sError: upgrade-interactive needs a terminal, use 'marn upgrade' in scripts%s\n", colors.Red, colors.Reset)
+		os.Exit(1)
+	}
+
+	poms, entries := loadUpgradeEntries(opts)
+	properties := getPomProperties(poms)
+
+	var choices []*UpgradeChoice
+
+	for _, entry := range entries {
+
+		if entry.Error != "" || !entry.HasUpdate() || isVersionRange(expandProperties(entry.Declared, properties)) {
+			continue
+		}
+
+		choice := &UpgradeChoice{Entry: entry}
+
+		for _, version := range []string{entry.LatestPatch, entry.LatestMinor, entry.LatestMajor} {
+
+			if version != "" && (len(choice.Versions) == 0 || choice.Versions[len(choice.Versions)-1] != version) {
+				choice.Versions = append(choice.Versions, version)
+			}
+		}
+
+		// Start at the version the target option asks for
+		for i, version := range choice.Versions {
+
+			if version == upgradeVersion(entry, opts.Target) {
+				choice.Selected = i
+			}
+		}
+
+		choices = append(choices, choice)
+	}
+
+	if len(choices) == 0 {
+		fmt.Printf("%s✓ Everything is up to date%s\n", colors.Green, colors.Reset)
+		return
+	}
+
+	fmt.Println()
+
+	if !pickUpgrades(choices) {
+		fmt.Printf("%sCancelled%s\n", colors.Yellow, colors.Reset)
+		return
+	}
+
+	var upgrades []Upgrade
+
+	for _, choice := range choices {
+
+		if choice.Checked {
+			upgrades = append(upgrades, Upgrade{Entry: choice.Entry, Version: choice.Versions[choice.Selected]})
+		}
+	}
+
+	if len(upgrades) == 0 {
+		fmt.Printf("%sNothing selected%s\n", colors.Yellow, colors.Reset)
+		return
+	}
+
+	fmt.Println()
+	applyUpgrades(poms, upgrades)
+}
+
+// pickUpgrades lets the user check upgrades and change their versions with the keyboard
+// Returns false when the user cancels
+func pickUpgrades(choices []*UpgradeChoice) bool {
+	keys, stop := startKeyReader()
+	if keys == nil {
+		return false
+	}
+	defer stop()
+
+	// Ctrl+C must not leave the terminal in key mode
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	cursor := 0
+	lines := 0
+
+	for {
+		lines = renderUpgradeChoices(choices, cursor, lines)
+
+		var key byte
+
+		select {
+		case <-interrupt:
+			return false
+
+		case key = <-keys:
+		}
+
+		if key == 0x1b {
+			key = readArrowKey(keys)
+		}
+
+		choice := choices[cursor]
+
+		switch key {
+		case 'k':
+			cursor = (cursor + len(choices) - 1) % len(choices)
+
+		case 'j':
+			cursor = (cursor + 1) % len(choices)
+
+		case 'h':
+
+			if choice.Selected > 0 {
+				choice.Selected--
+			}
+
+		case 'l':
+
+			if choice.Selected < len(choice.Versions)-1 {
+				choice.Selected++
+			}
+
+		case ' ':
+			choice.Checked = !choice.Checked
+
+		case 'a':
+			// Check everything, or uncheck everything when all are checked
+			all := true
+
+			for _, c := range choices {
+				all = all && c.Checked
+			}
+
+			for _, c := range choices {
+				c.Checked = !all
+			}
+
+		case '\r', '\n':
+			return true
+
+		case 'q', 0x1b:
+			return false
+		}
+	}
+}
+
+// readArrowKey reads the rest of an escape sequence and maps arrow keys to h, j, k and l
+// A lone escape is returned as is
+func readArrowKey(keys <-chan byte) byte {
+	var sequence []byte
+
+	for len(sequence) < 2 {
+
+		select {
+		case key := <-keys:
+			sequence = append(sequence, key)
+
+		case <-time.After(50 * time.Millisecond):
+			return 0x1b
+		}
+	}
+
+	if sequence[0] != '[' && sequence[0] != 'O' {
+		return 0
+	}
+
+	switch sequence[1] {
+	case 'A':
+		return 'k'
+	case 'B':
+		return 'j'
+	case 'C':
+		return 'l'
+	case 'D':
+		return 'h'
+	}
+
+	return 0
+}
+
+// renderUpgradeChoices draws the choices over the previous drawing and returns the number of lines
+func renderUpgradeChoices(choices []*UpgradeChoice, cursor int, previous int) int {
+	if previous > 0 {
+		// Move up and clear the previous drawing
+		fmt.Printf("\033[%dA\033[J", previous)
+	}
+
+	fmt.Printf("%sSelect upgrades%s  ↑/↓ move · space select · a all · ←/→ version · enter upgrade · q cancel\n", colors.Blue, colors.Reset)
+
+	width := 0
+	for _, choice := range choices {
+
+		if len(choice.Entry.Key()) > width {
+			width = len(choice.Entry.Key())
+		}
+	}
+
+	for i, choice := range choices {
+		pointer := " "
+		if i == cursor {
+			pointer = colors.Blue + "❯" + colors.Reset
+		}
+
+		box := "◯"
+		if choice.Checked {
+			box = colors.Green + "◉" + colors.Reset
+		}
+
+		version := choice.Versions[choice.Selected]
+		kind, color := upgradeKind(choice.Entry, version)
+
+		// Arrows show which other versions can be picked
+		before, after := " ", " "
+		if choice.Selected > 0 {
+			before = "‹"
+		}
+
+		if choice.Selected < len(choice.Versions)-1 {
+			after = "›"
+		}
+
+		name := choice.Entry.Key() + strings.Repeat(" ", width-len(choice.Entry.Key()))
+
+		fmt.Printf("%s %s %s  %s → %s %s%s%s %s %s\n", pointer, box, name, choice.Entry.Current, before, color, version, colors.Reset, after, kind)
+	}
+
+	return len(choices) + 1
+}
+
+// upgradeKind returns whether a version is a patch, minor or major update of an entry, with its color
+func upgradeKind(entry *OutdatedEntry, version string) (string, string) {
+	switch version {
+	case entry.LatestPatch:
+		return upgradePatch, colors.Green
+	case entry.LatestMinor:
+		return upgradeMinor, colors.Yellow
+	default:
+		return "major", colors.Red
+	}
+}
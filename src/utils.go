@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // isWindows returns true if running on Windows
@@ -32,34 +34,156 @@ func copyFile(src, dst string) error {
 	return err
 }
 
-// getMvnCommand returns the correct Maven command for the current OS
-func getMvnCommand() string {
-	mvnCmd := "mvn"
+// runMvnCommand runs a Maven command in the current project
+func runMvnCommand(args ...string) error {
+	return runMvnCommandIn(currentDir, args...)
+}
+
+// runMvnCommandIn runs a Maven command in a project directory
+// The output is shown, kept in .marn/logs and parsed into diagnostics for --format
+func runMvnCommandIn(projectDir string, args ...string) error {
+	maven := findMaven(projectDir)
+
+	cmd := maven.Command(context.Background(), projectDir, args...)
+
+	// Display command with $ prefix
+	fmt.Fprintf(console, "%s$ %s %s%s\n", colors.Blue, maven.Name, strings.Join(cmd.Args[1+len(maven.Args):], " "), colors.Reset)
+
+	cmd.Args = append(cmd.Args, mavenColorArgs(console)...)
+	cmd.Stdout = console
+	cmd.Stderr = os.Stderr
+
+	// Keep a copy of the output in .marn/logs
+	log, err := newBuildLog(projectDir, maven, args)
+	if err == nil {
+		defer log.Close()
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, log)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, log)
+	}
+
+	// Collect compiler errors and test failures
+	collector := newDiagnosticCollector()
+	lines := newLineWriter(collector.AddLine)
+	cmd.Stdout = io.MultiWriter(cmd.Stdout, lines)
+	cmd.Stderr = io.MultiWriter(cmd.Stderr, lines)
+
+	start := time.Now()
+	err = cmd.Run()
+
+	lines.Flush()
+
+	if projectDir == currentDir {
+		collector.AddTestReports(start)
+	}
+
+	reportDiagnostics(collector.Diagnostics())
 
+	if err != nil {
+
+		// Summarize the noisy output
+		if outputFormat == "" {
+			fmt.Fprintln(console)
+			printDiagnostics(collector.Diagnostics())
+		}
+
+		if log != nil {
+			fmt.Fprintf(console, "%sFull log: %s%s\n", colors.Yellow, log.RelPath(), colors.Reset)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// getMarnCommand returns the command line that starts this marn binary
+func getMarnCommand() string {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "marn"
+	}
+
+	// PowerShell needs the call operator to run a quoted path
 	if isWindows() {
+		return "& \"" + execPath + "\""
+	}
 
-		// Check if mvn.cmd exists
-		if _, err := exec.LookPath("mvn.cmd"); err == nil {
-			mvnCmd = "mvn.cmd"
+	return "\"" + execPath + "\""
+}
+
+// splitArgs splits a command line into arguments
+// Whitespace separates arguments unless it is inside single or double quotes
+func splitArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+
+		default:
+			current.WriteRune(r)
+			inArg = true
 		}
 	}
 
-	return mvnCmd
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
 }
 
-// runMvnCommand runs a Maven command
-func runMvnCommand(args ...string) error {
-	mvnCmd := getMvnCommand()
+// parseDuration parses a duration with a unit such as 10s, 500ms or 1m
+// Plain numbers are seconds
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
 
-	// Display command with $ prefix
-	fmt.Printf("%s$ %s %s%s\n", colors.Blue, mvnCmd, strings.Join(args, " "), colors.Reset)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		d, err = time.ParseDuration(value + "s")
+	}
 
-	cmd := exec.Command(mvnCmd, args...)
-	cmd.Dir = currentDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration '%s'", value)
+	}
 
-	return cmd.Run()
+	return d, nil
+}
+
+// getDurationProperty reads a duration from a pom.xml property
+// Warns and returns fallback when the value can't be parsed
+func getDurationProperty(name string, fallback time.Duration) time.Duration {
+	value := getProperty(name)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := parseDuration(value)
+	if err != nil {
+		fmt.Printf("%sWarning: %s has an %v, using %v%s\n", colors.Yellow, name, err, fallback, colors.Reset)
+		return fallback
+	}
+
+	return d
 }
 
 // runShellCommand runs a shell command
@@ -68,8 +192,18 @@ func runShellCommand(command string) error {
 	expandedCommand := expandEnvVars(command)
 
 	// Display command with $ prefix
-	fmt.Printf("%s$ %s%s\n", colors.Blue, expandedCommand, colors.Reset)
+	fmt.Fprintf(console, "%s$ %s%s\n", colors.Blue, expandedCommand, colors.Reset)
 
+	cmd := newShellCommand(expandedCommand)
+	cmd.Stdout = console
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	return cmd.Run()
+}
+
+// newShellCommand creates the platform shell command for an already expanded command line
+func newShellCommand(expandedCommand string) *exec.Cmd {
 	var cmd *exec.Cmd
 
 	if isWindows() {
@@ -142,9 +276,6 @@ function which {
 	}
 
 	cmd.Dir = currentDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
 
-	return cmd.Run()
+	return cmd
 }
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Qualifiers ordered like Maven orders them, a release has the empty qualifier
+var versionQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// Qualifier aliases used by Maven
+var versionQualifierAliases = map[string]string{
+	"a":       "alpha",
+	"b":       "beta",
+	"m":       "milestone",
+	"cr":      "rc",
+	"ga":      "",
+	"final":   "",
+	"release": "",
+}
+
+// versionItem is a number or a qualifier of a version
+type versionItem struct {
+	number    string
+	qualifier string
+	isNumber  bool
+}
+
+// parseVersion splits a Maven version into numbers and qualifiers
+// 1.2.0-RC1 becomes 1, 2, 0, rc, 1
+func parseVersion(version string) []versionItem {
+	var items []versionItem
+	var current strings.Builder
+	currentIsNumber := false
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+
+		text := strings.ToLower(current.String())
+		current.Reset()
+
+		if currentIsNumber {
+			number := strings.TrimLeft(text, "0")
+			items = append(items, versionItem{number: number, isNumber: true})
+			return
+		}
+
+		if alias, ok := versionQualifierAliases[text]; ok {
+			text = alias
+		}
+
+		items = append(items, versionItem{qualifier: text})
+	}
+
+	for _, r := range version {
+
+		switch {
+		case r == '.' || r == '-' || r == '_':
+			flush()
+
+		case unicode.IsDigit(r):
+
+			if current.Len() > 0 && !currentIsNumber {
+				flush()
+			}
+
+			currentIsNumber = true
+			current.WriteRune(r)
+
+		default:
+
+			if current.Len() > 0 && currentIsNumber {
+				flush()
+			}
+
+			currentIsNumber = false
+			current.WriteRune(r)
+		}
+	}
+
+	flush()
+	return items
+}
+
+// compareVersions compares two Maven versions
+// Returns a negative number when a is older, 0 when equal and a positive number when newer
+func compareVersions(a, b string) int {
+	itemsA, itemsB := parseVersion(a), parseVersion(b)
+
+	for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+		// A missing part is 0 for numbers and a release for qualifiers
+		itemA, itemB := versionItem{isNumber: true}, versionItem{isNumber: true}
+
+		if i < len(itemsA) {
+			itemA = itemsA[i]
+		} else if i < len(itemsB) && !itemsB[i].isNumber {
+			itemA = versionItem{}
+		}
+
+		if i < len(itemsB) {
+			itemB = itemsB[i]
+		} else if !itemA.isNumber {
+			itemB = versionItem{}
+		}
+
+		if c := compareVersionItems(itemA, itemB); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareVersionItems compares a single part of two versions
+// Numbers are newer than qualifiers
+func compareVersionItems(a, b versionItem) int {
+	switch {
+	case a.isNumber && b.isNumber:
+
+		if len(a.number) != len(b.number) {
+			return len(a.number) - len(b.number)
+		}
+
+		return strings.Compare(a.number, b.number)
+
+	case a.isNumber:
+		return 1
+
+	case b.isNumber:
+		return -1
+	}
+
+	rankA, rankB := qualifierRank(a.qualifier), qualifierRank(b.qualifier)
+
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	return strings.Compare(a.qualifier, b.qualifier)
+}
+
+// qualifierRank returns the position of a qualifier, unknown qualifiers come last
+func qualifierRank(qualifier string) int {
+	for i, known := range versionQualifiers {
+
+		if qualifier == known {
+			return i
+		}
+	}
+
+	return len(versionQualifiers)
+}
+
+// isStableVersion reports whether a version is not a snapshot or pre-release
+func isStableVersion(version string) bool {
+	for _, item := range parseVersion(version) {
+
+		if item.isNumber {
+			continue
+		}
+
+		switch item.qualifier {
+		case "alpha", "beta", "milestone", "rc", "snapshot", "preview", "ea", "dev":
+			return false
+		}
+	}
+
+	return true
+}
+
+// latestVersion returns the newest stable version
+// Pre-releases are only considered when there is no stable version
+func latestVersion(versions []string) string {
+	latest := ""
+
+	for _, version := range versions {
+
+		if isStableVersion(version) && (latest == "" || compareVersions(version, latest) > 0) {
+			latest = version
+		}
+	}
+
+	if latest != "" {
+		return latest
+	}
+
+	for _, version := range versions {
+
+		if latest == "" || compareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+
+	return latest
+}
+
+// latestUpdates returns the newest versions with the same major and minor, with the same major, and overall
+// A result is "" when there is no newer version, pre-releases only count when current is one
+func latestUpdates(current string, versions []string) (patch, minor, major string) {
+	currentMajor, currentMinor := versionMajorMinor(current)
+	stable := isStableVersion(current)
+	flavor := versionFlavor(current)
+
+	newer := func(version, latest string) bool {
+		return latest == "" || compareVersions(version, latest) > 0
+	}
+
+	for _, version := range versions {
+
+		if compareVersions(version, current) <= 0 || (stable && !isStableVersion(version)) {
+			continue
+		}
+
+		// 32.1.2-jre is only updated to another -jre version, not to -android
+		if versionFlavor(version) != flavor {
+			continue
+		}
+
+		versionMajor, versionMinor := versionMajorMinor(version)
+
+		if newer(version, major) {
+			major = version
+		}
+
+		if versionMajor == currentMajor && newer(version, minor) {
+			minor = version
+		}
+
+		if versionMajor == currentMajor && versionMinor == currentMinor && newer(version, patch) {
+			patch = version
+		}
+	}
+
+	return patch, minor, major
+}
+
+// versionFlavor returns the variant qualifier at the end of a version, like jre for 32.1.2-jre
+// Release and pre-release qualifiers are not a variant
+func versionFlavor(version string) string {
+	segments := strings.FieldsFunc(strings.ToLower(version), func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	})
+
+	if len(segments) == 0 {
+		return ""
+	}
+
+	last := segments[len(segments)-1]
+
+	qualifier := strings.TrimRightFunc(last, unicode.IsDigit)
+	if alias, ok := versionQualifierAliases[qualifier]; ok {
+		qualifier = alias
+	}
+
+	switch qualifier {
+	case "", "alpha", "beta", "milestone", "rc", "snapshot", "sp", "preview", "ea", "dev":
+		return ""
+	}
+
+	return last
+}
+
+// versionMajorMinor returns the first two numbers of a version, like 1 and 2 for 1.2.3
+func versionMajorMinor(version string) (string, string) {
+	numbers := []string{"", ""}
+
+	for i, item := range parseVersion(version) {
+
+		if i >= len(numbers) || !item.isNumber {
+			break
+		}
+
+		numbers[i] = item.number
+	}
+
+	return numbers[0], numbers[1]
+}
+
+// versionProperty returns the property a version comes from, like guava.version for ${guava.version}
+// Returns "" when the version is not a single property
+func versionProperty(version string) string {
+	if strings.HasPrefix(version, "${") && strings.HasSuffix(version, "}") && strings.Count(version, "${") == 1 {
+		return version[2 : len(version)-1]
+	}
+
+	return ""
+}
+
+// isVersionRange reports whether a version is a range like [1.0,2.0)
+func isVersionRange(version string) bool {
+	return strings.HasPrefix(version, "[") || strings.HasPrefix(version, "(")
+}
+
+// versionInRange reports whether a version satisfies a range like [1.0,2.0) or (,1.0],[1.2,)
+func versionInRange(version, spec string) bool {
+	spec = strings.ReplaceAll(spec, " ", "")
+
+	for spec != "" {
+		end := strings.IndexAny(spec, "])")
+		if end < 0 {
+			return false
+		}
+
+		if matchVersionRange(version, spec[:end+1]) {
+			return true
+		}
+
+		spec = strings.TrimPrefix(spec[end+1:], ",")
+	}
+
+	return false
+}
+
+// matchVersionRange reports whether a version satisfies a single range like [1.0,2.0) or [1.5]
+func matchVersionRange(version, r string) bool {
+	if len(r) < 2 {
+		return false
+	}
+
+	bounds := r[1 : len(r)-1]
+
+	// [1.5] only allows 1.5
+	if !strings.Contains(bounds, ",") {
+		return compareVersions(version, bounds) == 0
+	}
+
+	lower, upper, _ := strings.Cut(bounds, ",")
+
+	if lower != "" {
+		c := compareVersions(version, lower)
+
+		if c < 0 || (c == 0 && r[0] != '[') {
+			return false
+		}
+	}
+
+	if upper != "" {
+		c := compareVersions(version, upper)
+
+		if c > 0 || (c == 0 && r[len(r)-1] != ']') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// latestInRange returns the newest version that satisfies a range
+func latestInRange(spec string, versions []string) string {
+	var matching []string
+
+	for _, version := range versions {
+
+		if versionInRange(version, spec) {
+			matching = append(matching, version)
+		}
+	}
+
+	return latestVersion(matching)
+}
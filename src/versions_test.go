@@ -0,0 +1,215 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1", 0},
+		{"1.0.0", "1", 0},
+		{"1.0-ga", "1.0", 0},
+		{"1.0.final", "1.0", 0},
+		{"1.0.1", "1.0", 1},
+		{"1.10", "1.9", 1},
+		{"1.010", "1.10", 0},
+		{"2.0", "1.99.99", 1},
+		{"33.0.0-jre", "32.1.2-jre", 1},
+		{"1.0-alpha1", "1.0-beta1", -1},
+		{"1.0-a1", "1.0-alpha1", 0},
+		{"1.0-beta2", "1.0-m1", -1},
+		{"1.0-M1", "1.0-RC1", -1},
+		{"1.0-CR1", "1.0-rc1", 0},
+		{"1.0-RC1", "1.0-SNAPSHOT", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0", "1.0-sp1", -1},
+		{"1.0-RC2", "1.0-RC10", -1},
+		{"1.0", "1.0.1-SNAPSHOT", -1},
+		{"5.10.1", "5.9.3", 1},
+	}
+
+	for _, tt := range tests {
+		got := compareVersions(tt.a, tt.b)
+
+		if sign(got) != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+
+		// Swapping the versions must flip the result
+		if sign(compareVersions(tt.b, tt.a)) != -tt.want {
+			t.Errorf("compareVersions(%q, %q) is not the opposite of compareVersions(%q, %q)", tt.b, tt.a, tt.a, tt.b)
+		}
+	}
+}
+
+func TestIsStableVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.0", true},
+		{"32.1.2-jre", true},
+		{"1.0.Final", true},
+		{"1.0-sp1", true},
+		{"1.0-SNAPSHOT", false},
+		{"1.0-RC1", false},
+		{"1.0-M2", false},
+		{"1.0-beta", false},
+		{"1.0-alpha-1", false},
+		{"21-ea", false},
+	}
+
+	for _, tt := range tests {
+
+		if got := isStableVersion(tt.version); got != tt.want {
+			t.Errorf("isStableVersion(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestVersionFlavor(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.2.3", ""},
+		{"32.1.2-jre", "jre"},
+		{"33.1.0-android", "android"},
+		{"1.0-RC1", ""},
+		{"1.0-SNAPSHOT", ""},
+		{"5.0.0.Final", ""},
+		{"1.0-sp2", ""},
+		{"2.1.0-jdk8", "jdk8"},
+		{"2.1.0-jdk11", "jdk11"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+
+		if got := versionFlavor(tt.version); got != tt.want {
+			t.Errorf("versionFlavor(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestLatestUpdates(t *testing.T) {
+	guava := []string{"31.1-jre", "32.1.2-jre", "32.1.3-jre", "32.1.3-android", "33.0.0-jre", "33.1.0-android", "33.1.0-jre"}
+	junit := []string{"5.9.0", "5.9.3", "5.10.0", "5.10.1", "5.11.0-M1", "6.0.0-RC1"}
+
+	tests := []struct {
+		name                string
+		current             string
+		versions            []string
+		patch, minor, major string
+	}{
+		{"jre stays jre", "32.1.2-jre", guava, "32.1.3-jre", "32.1.3-jre", "33.1.0-jre"},
+		{"android stays android", "32.1.3-android", guava, "", "", "33.1.0-android"},
+		{"up to date", "33.1.0-jre", guava, "", "", ""},
+		{"pre-releases are skipped", "5.9.0", junit, "5.9.3", "5.10.1", "5.10.1"},
+		{"pre-releases are offered to pre-releases", "5.11.0-M1", junit, "", "", "6.0.0-RC1"},
+		{"older versions are ignored", "5.10.1", junit, "", "", ""},
+		{"no versions", "1.0", nil, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		patch, minor, major := latestUpdates(tt.current, tt.versions)
+
+		if patch != tt.patch || minor != tt.minor || major != tt.major {
+			t.Errorf("%s: latestUpdates(%q) = %q, %q, %q, want %q, %q, %q", tt.name, tt.current, patch, minor, major, tt.patch, tt.minor, tt.major)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	tests := []struct {
+		versions []string
+		want     string
+	}{
+		{[]string{"1.0", "1.2", "1.10", "1.9"}, "1.10"},
+		{[]string{"1.0", "2.0-RC1"}, "1.0"},
+		{[]string{"2.0-RC1", "2.0-M1"}, "2.0-RC1"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+
+		if got := latestVersion(tt.versions); got != tt.want {
+			t.Errorf("latestVersion(%q) = %q, want %q", tt.versions, got, tt.want)
+		}
+	}
+}
+
+func TestVersionInRange(t *testing.T) {
+	tests := []struct {
+		version string
+		spec    string
+		want    bool
+	}{
+		{"1.0", "[1.0,2.0)", true},
+		{"1.5", "[1.0,2.0)", true},
+		{"2.0", "[1.0,2.0)", false},
+		{"2.0", "[1.0,2.0]", true},
+		{"1.0", "(1.0,2.0)", false},
+		{"0.9", "[1.0,)", false},
+		{"9.0", "[1.0,)", true},
+		{"1.0", "(,1.0]", true},
+		{"1.1", "(,1.0]", false},
+		{"1.5", "[1.5]", true},
+		{"1.5.1", "[1.5]", false},
+		{"1.1", "(,1.0],[1.2,)", false},
+		{"1.3", "(,1.0],[1.2,)", true},
+		{"1.3", "(,1.0], [1.2,)", true},
+		{"1.0", "[1.0", false},
+	}
+
+	for _, tt := range tests {
+
+		if got := versionInRange(tt.version, tt.spec); got != tt.want {
+			t.Errorf("versionInRange(%q, %q) = %v, want %v", tt.version, tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestLatestInRange(t *testing.T) {
+	versions := []string{"3.9", "3.10", "3.12.0", "4.0", "4.1-RC1"}
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"[3.10,4.0)", "3.12.0"},
+		{"[3.10,)", "4.0"},
+		{"[5.0,)", ""},
+		{"[3.9]", "3.9"},
+	}
+
+	for _, tt := range tests {
+
+		if got := latestInRange(tt.spec, versions); got != tt.want {
+			t.Errorf("latestInRange(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestIsVersionRange(t *testing.T) {
+	for version, want := range map[string]bool{"[1.0,2.0)": true, "(,1.0]": true, "1.0": false, "${v}": false} {
+
+		if got := isVersionRange(version); got != want {
+			t.Errorf("isVersionRange(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+// sign reduces a comparison result to -1, 0 or 1
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
@@ -1,15 +1,14 @@
 package main
 
 import (
-    "bufio"
+    "context"
     "fmt"
-    "io"
     "os"
-    "os/exec"
     "os/signal"
     "path/filepath"
-    "regexp"
+    "sort"
     "strings"
+    "sync"
     "syscall"
     "time"
 
@@ -25,49 +24,169 @@ func watchMode() {
         os.Exit(1)
     }
 
+    opts, err := parseWatchArgs(os.Args[2:])
+    if err != nil {
+        fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+        os.Exit(1)
+    }
+
+    startWatchMode(opts)
+}
+
+// testWatchMode implements 'marn test --watch', watch mode running the affected tests
+func testWatchMode(args []string) {
+    opts, err := parseWatchArgs(args)
+    if err != nil {
+        fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+        os.Exit(1)
+    }
+
+    opts.Test = "affected"
+    startWatchMode(opts)
+}
+
+// startWatchMode runs watch mode until it is stopped
+func startWatchMode(opts WatchOptions) {
+
     // Get watch configuration
     config := loadWatchConfig()
+    config.Debug = opts.Debug
+    config.Verbose = opts.Verbose
+
+    // watch.test=all is kept, otherwise the option decides
+    if opts.Test != "" && config.Test == "" {
+        config.Test = opts.Test
+    }
+
+    addTestDirs(&config)
+
+    // Hot-swapping needs a debug agent and an application started by marn
+    if config.Reload == "hotswap" {
+
+        if !config.Debug.Enabled {
+            config.Debug = DebugOptions{Enabled: true, Port: defaultDebugPort}
+        }
+
+        if config.PostCommand == "" {
+            config.PostCommand = getMarnCommand() + " run --exec"
+        }
+    }
+
+    // Post commands running 'marn run' pick up the debug port from the environment
+    if config.Debug.Enabled {
+        os.Setenv("MARN_DEBUG", config.Debug.Spec())
+    }
 
     // Get local dependencies
     localDeps := getLocalDependencies()
 
+    // Load the per-path watch rules
+    rules := loadWatchRules(config)
+
     // Print configuration
-    printWatchBanner(config, localDeps)
+    printWatchBanner(config, localDeps, rules)
 
     // Build local dependencies first
     if err := buildLocalDependencies(config.SkipTests); err != nil {
         os.Exit(1)
     }
 
+    // The post command runs as a managed process restarted after every build
+    app := &ManagedApp{
+        Command:     config.PostCommand,
+        GracePeriod: config.GracePeriod,
+    }
+
+    var swapper *Hotswapper
+    if config.Reload == "hotswap" {
+        swapper = newHotswapper(config.Debug)
+    }
+
     // Initial build
     fmt.Printf("%sRunning initial build...%s\n", colors.Green, colors.Reset)
 
-    success, _ := runMvnBuild(config.BuildCommand, config.SkipTests)
+    start := time.Now()
+
+    success, err := runMvnBuild(context.Background(), config.BuildCommand, config.SkipTests)
+    notifyBuild(config.Notify, success, err, time.Since(start))
+
     if success {
         fmt.Printf("%s✓ Initial build complete!%s\n", colors.Green, colors.Reset)
 
         if config.PostCommand != "" {
-            fmt.Printf("%sRunning post command: %s%s\n", colors.Yellow, config.PostCommand, colors.Reset)
-            runShellCommand(config.PostCommand)
+            reloadApp(app, swapper, false)
         }
     } else {
         fmt.Printf("%s✗ Initial build failed!%s\n", colors.Red, colors.Reset)
+        restoreTerminalTitle()
         os.Exit(1)
     }
 
     fmt.Println()
 
     // Start watching
-    startWatcher(config, localDeps)
+    startWatcher(config, localDeps, rules, app, swapper)
+
+    // Stop the application when watch mode ends
+    app.Stop()
+}
+
+// WatchOptions holds the options passed to 'marn watch'
+type WatchOptions struct {
+    Debug   DebugOptions
+    Verbose bool
+    Test    string
 }
 
 // WatchConfig holds watch mode configuration
 type WatchConfig struct {
-    WatchDirs    string
-    BuildCommand string
-    SkipTests    bool
-    DebounceTime time.Duration
-    PostCommand  string
+    WatchDirs      string
+    BuildCommand   string
+    SkipTests      bool
+    DebounceTime   time.Duration
+    PostCommand    string
+    GracePeriod    time.Duration
+    Reload         string
+    CancelOnChange bool
+    Include        []string
+    Ignore         []string
+    Test           string
+    Notify         string
+    Verbose        bool
+    Debug          DebugOptions
+}
+
+// WatchFilter decides which file events trigger a rebuild
+type WatchFilter struct {
+    include   *PathMatcher
+    ignore    *PathMatcher
+    localDeps []string
+}
+
+// parseWatchArgs parses the arguments given to 'marn watch'
+func parseWatchArgs(args []string) (WatchOptions, error) {
+    var opts WatchOptions
+
+    for _, arg := range args {
+
+        switch {
+        case arg == "--debug" || strings.HasPrefix(arg, "--debug="):
+            debug, err := parseDebugSpec(strings.TrimPrefix(strings.TrimPrefix(arg, "--debug"), "="))
+            if err != nil {
+                return opts, err
+            }
+
+            opts.Debug = debug
+
+        case arg == "--verbose":
+            opts.Verbose = true
+
+        default:
+            return opts, fmt.Errorf("unknown option '%s'", arg)
+        }
+    }
+
+    return opts, nil
 }
 
 // loadWatchConfig loads watch configuration from pom.xml
@@ -78,6 +197,9 @@ func loadWatchConfig() WatchConfig {
         SkipTests:    true,
         DebounceTime: 2 * time.Second,
         PostCommand:  "",
+        GracePeriod:  10 * time.Second,
+        Reload:       "restart",
+        Ignore:       append([]string{}, defaultWatchIgnore...),
     }
 
     // Override with pom.xml values
@@ -93,28 +215,80 @@ func loadWatchConfig() WatchConfig {
         config.SkipTests = false
     }
 
-    if debounce := getProperty("watch.debounceTime"); debounce != "" {
+    config.DebounceTime = getDurationProperty("watch.debounceTime", config.DebounceTime)
 
-        if d, err := time.ParseDuration(debounce + "s"); err == nil {
-            config.DebounceTime = d
-        }
+    if include := getProperty("watch.include"); include != "" {
+        config.Include = strings.Fields(include)
+    }
+
+    // Project patterns are added to the defaults and can re-include files with !
+    if ignore := getProperty("watch.ignore"); ignore != "" {
+        config.Ignore = append(config.Ignore, strings.Fields(ignore)...)
+    }
+
+    if test := getProperty("watch.test"); test == "affected" || test == "all" {
+        config.Test = test
+    } else if test != "" {
+        fmt.Printf("%sWarning: Unknown watch.test '%s', tests are not run%s\n", colors.Yellow, test, colors.Reset)
+    }
+
+    if notify, err := parseNotifyMode(getProperty("watch.notify")); err == nil {
+        config.Notify = notify
+    } else {
+        fmt.Printf("%sWarning: %v, notifications are off%s\n", colors.Yellow, err, colors.Reset)
+    }
+
+    if cancel := getProperty("watch.cancelOnChange"); cancel == "true" {
+        config.CancelOnChange = true
     }
 
     if post := getProperty("watch.postCommand"); post != "" {
         config.PostCommand = post
     }
 
+    if reload := getProperty("watch.reload"); reload == "hotswap" || reload == "restart" {
+        config.Reload = reload
+    } else if reload != "" {
+        fmt.Printf("%sWarning: Unknown watch.reload '%s', using restart%s\n", colors.Yellow, reload, colors.Reset)
+    }
+
+    config.GracePeriod = getDurationProperty("watch.gracePeriod", config.GracePeriod)
+
     return config
 }
 
+// addTestDirs watches the test sources when watch mode runs tests
+func addTestDirs(config *WatchConfig) {
+
+    if config.Test == "" {
+        return
+    }
+
+    dirs := strings.Fields(config.WatchDirs)
+
+    for _, dir := range []string{"src/test/java", "src/test/resources"} {
+
+        if !containsString(dirs, dir) {
+            dirs = append(dirs, dir)
+        }
+    }
+
+    config.WatchDirs = strings.Join(dirs, " ")
+}
+
 // printWatchBanner prints the watch mode banner
-func printWatchBanner(config WatchConfig, localDeps []string) {
+func printWatchBanner(config WatchConfig, localDeps []string, rules []*WatchRule) {
     fmt.Printf("%s╔════════════════════════════════════════╗%s\n", colors.Blue, colors.Reset)
     fmt.Printf("%s║     Maven Watch Build (Generic)      ║%s\n", colors.Blue, colors.Reset)
     fmt.Printf("%s╚════════════════════════════════════════╝%s\n", colors.Blue, colors.Reset)
     fmt.Println()
     fmt.Printf("%sConfiguration:%s\n", colors.Yellow, colors.Reset)
     fmt.Printf("  %sWatching:%s %s\n", colors.Green, colors.Reset, config.WatchDirs)
+    fmt.Printf("  %sMaven:%s %s\n", colors.Green, colors.Reset, findMaven(currentDir).Name)
+
+    if args := getMavenArgs(); len(args) > 0 {
+        fmt.Printf("  %sMaven Args:%s %s\n", colors.Green, colors.Reset, strings.Join(args, " "))
+    }
 
     if len(localDeps) > 0 {
         fmt.Printf("  %sLocal Dependencies:%s\n", colors.Green, colors.Reset)
@@ -124,12 +298,45 @@ func printWatchBanner(config WatchConfig, localDeps []string) {
         }
     }
 
+    if len(config.Include) > 0 {
+        fmt.Printf("  %sInclude:%s %s\n", colors.Green, colors.Reset, strings.Join(config.Include, " "))
+    }
+
+    if len(config.Ignore) > len(defaultWatchIgnore) {
+        fmt.Printf("  %sIgnore:%s %s\n", colors.Green, colors.Reset, strings.Join(config.Ignore[len(defaultWatchIgnore):], " "))
+    }
+
     fmt.Printf("  %sCommand:%s %s\n", colors.Green, colors.Reset, config.BuildCommand)
+
+    // The default rule is described by the lines above
+    if len(rules) > 1 {
+        fmt.Printf("  %sRules:%s\n", colors.Green, colors.Reset)
+
+        for _, rule := range rules[:len(rules)-1] {
+            fmt.Printf("    - %s: %s -> %s\n", rule.Name, strings.Join(rule.Paths, " "), rule.Describe())
+        }
+    }
+
     fmt.Printf("  %sSkip Tests:%s %v\n", colors.Green, colors.Reset, config.SkipTests)
     fmt.Printf("  %sDebounce:%s %v\n", colors.Green, colors.Reset, config.DebounceTime)
+    fmt.Printf("  %sCancel On Change:%s %v\n", colors.Green, colors.Reset, config.CancelOnChange)
+
+    if config.Test != "" {
+        fmt.Printf("  %sTests:%s %s\n", colors.Green, colors.Reset, config.Test)
+    }
+
+    if config.Notify != "" {
+        fmt.Printf("  %sNotify:%s %s\n", colors.Green, colors.Reset, config.Notify)
+    }
 
     if config.PostCommand != "" {
         fmt.Printf("  %sPost Command:%s %s\n", colors.Green, colors.Reset, config.PostCommand)
+        fmt.Printf("  %sGrace Period:%s %v\n", colors.Green, colors.Reset, config.GracePeriod)
+        fmt.Printf("  %sReload:%s %s\n", colors.Green, colors.Reset, config.Reload)
+    }
+
+    if config.Debug.Enabled {
+        fmt.Printf("  %sDebug:%s %s (MARN_DEBUG=%s)\n", colors.Green, colors.Reset, config.Debug.Address(), config.Debug.Spec())
     }
 
     fmt.Println()
@@ -137,71 +344,209 @@ func printWatchBanner(config WatchConfig, localDeps []string) {
     fmt.Println()
 }
 
+// WatchSession holds the state of a running watch mode
+type WatchSession struct {
+    config    WatchConfig
+    localDeps []string
+    rules     []*WatchRule
+    filter    *WatchFilter
+    watcher   *FileWatcher
+    roots     map[string]bool
+    app       *ManagedApp
+    swapper   *Hotswapper
+
+    // The watcher goroutine reads the filter through SkipDir, filterMu guards replacing it
+    filterMu sync.Mutex
+
+    // Build state, builds run one at a time and each rule queues at most one follow-up
+    building    bool
+    queue       []*WatchRule
+    cancelBuild context.CancelFunc
+    buildDone   chan BuildResult
+    ruleFired   chan *WatchRule
+
+    // pom.xml, its parents and .env, a change reloads the configuration
+    configFiles     map[string]bool
+    configChanges   map[string]bool
+    configTimer     *time.Timer
+    configFired     chan struct{}
+    reloadPending   bool
+    depsFingerprint string
+
+    // Keyboard controls, keys is nil when stdin is not a terminal
+    keys   <-chan byte
+    paused bool
+}
+
+// BuildResult is sent by a finished watch build
+type BuildResult struct {
+    Success      bool
+    ReloadConfig bool
+
+    // Changes a cancelled build did not cover, they are built again
+    Unbuilt map[string]fsnotify.Op
+}
+
 // startWatcher starts the file watcher
-func startWatcher(config WatchConfig, localDeps []string) {
+func startWatcher(config WatchConfig, localDeps []string, rules []*WatchRule, app *ManagedApp, swapper *Hotswapper) {
 
     // Create watcher
-    watcher, err := fsnotify.NewWatcher()
+    watcher, err := newFileWatcher()
     if err != nil {
         fmt.Printf("%sError: Could not create file watcher: %v%s\n", colors.Red, err, colors.Reset)
         os.Exit(1)
     }
     defer watcher.Close()
 
-    // Add directories to watch
-    for _, dir := range strings.Fields(config.WatchDirs) {
-        dirPath := filepath.Join(currentDir, dir)
+    // The build status is taken out of the terminal title when watch mode ends
+    defer restoreTerminalTitle()
+
+    s := &WatchSession{
+        config:      config,
+        localDeps:   localDeps,
+        watcher:     watcher,
+        roots:       make(map[string]bool),
+        app:         app,
+        swapper:     swapper,
+        cancelBuild: func() {},
+        buildDone:   make(chan BuildResult),
+        ruleFired:   make(chan *WatchRule, 16),
+
+        configChanges:   make(map[string]bool),
+        configFired:     make(chan struct{}, 1),
+        depsFingerprint: getDependencyFingerprint(),
+    }
+
+    // Ignored directories such as target/ are not watched at all
+    watcher.SkipDir = func(path string) bool {
+        s.filterMu.Lock()
+        filter := s.filter
+        s.filterMu.Unlock()
 
-        if _, err := os.Stat(dirPath); err == nil {
-            addDirRecursive(watcher, dirPath)
-        }
+        ignored, _ := filter.ignore.Match(filter.relPath(path), true)
+        return ignored
+    }
+
+    // Single key commands, the reader stops and the terminal is restored when watch mode ends
+    keys, stop := startKeyReader()
+    defer stop()
+
+    s.keys = keys
+
+    if keys != nil {
+        printKeyHelp()
     }
 
+    s.applyConfig(rules)
+    s.run()
+}
+
+// applyConfig sets the rules and filter and updates the watched directories
+func (s *WatchSession) applyConfig(rules []*WatchRule) {
+    s.rules = rules
+
+    filter := newWatchFilter(s.config, s.localDeps)
+    s.filterMu.Lock()
+    s.filter = filter
+    s.filterMu.Unlock()
+
+    roots := ruleRoots(s.rules)
+
     // Add local dependency directories
-    for _, dep := range localDeps {
-        srcMain := filepath.Join(dep, "src", "main", "java")
-        srcResources := filepath.Join(dep, "src", "main", "resources")
+    for _, dep := range s.localDeps {
+        roots[filepath.Join(dep, "src", "main", "java")] = true
+        roots[filepath.Join(dep, "src", "main", "resources")] = true
+    }
+
+    // Watch the configuration files through their directories
+    s.configFiles = map[string]bool{
+        pomFile:                            true,
+        filepath.Join(currentDir, ".env"): true,
+    }
+
+    for _, parent := range getParentPoms() {
+        s.configFiles[parent] = true
+    }
+
+    for file := range s.configFiles {
 
-        if _, err := os.Stat(srcMain); err == nil {
-            addDirRecursive(watcher, srcMain)
+        if _, exists := roots[filepath.Dir(file)]; !exists {
+            roots[filepath.Dir(file)] = false
         }
+    }
+
+    for dir := range s.roots {
 
-        if _, err := os.Stat(srcResources); err == nil {
-            addDirRecursive(watcher, srcResources)
+        if _, keep := roots[dir]; !keep {
+            s.watcher.RemoveTree(dir)
         }
     }
 
+    for dir, recursive := range roots {
+
+        if _, err := os.Stat(dir); err != nil {
+            continue
+        }
+
+        if recursive {
+            s.watcher.AddTree(dir)
+        } else {
+            s.watcher.AddDir(dir)
+        }
+    }
+
+    s.roots = roots
+}
+
+// run is the watch loop
+func (s *WatchSession) run() {
+
     // Handle Ctrl+C
     sigChan := make(chan os.Signal, 1)
     signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-    // Debounce timer
-    var lastBuild time.Time
-
-    // Watch loop
     for {
         select {
-        case event, ok := <-watcher.Events:
+        case event, ok := <-s.watcher.Events:
 
             if !ok {
                 return
             }
 
-            // Skip non-relevant events
-            if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
-                continue
+            s.handleEvent(event)
+
+        case rule := <-s.ruleFired:
+            s.ruleReady(rule)
+
+        case <-s.configFired:
+
+            // The running build still uses the current configuration
+            if s.building || s.paused {
+                s.reloadPending = true
+            } else {
+                s.reloadConfig(true)
+            }
+
+        case key := <-s.keys:
+
+            if !s.handleKey(key) {
+                s.stop()
+                return
             }
 
-            // Debounce check
-            if time.Since(lastBuild) < config.DebounceTime {
-                continue
+        case result := <-s.buildDone:
+            s.building = false
+            s.cancelBuild()
+            s.requeueChanges(result.Unbuilt)
+
+            // A full rebuild already picked up dependency changes
+            if result.ReloadConfig || (s.reloadPending && !s.paused) {
+                s.reloadConfig(!result.ReloadConfig)
             }
 
-            // Handle the file change
-            handleFileChange(event, config, localDeps)
-            lastBuild = time.Now()
+            s.startNextBuild()
 
-        case err, ok := <-watcher.Errors:
+        case err, ok := <-s.watcher.Errors:
 
             if !ok {
                 return
@@ -211,34 +556,612 @@ func startWatcher(config WatchConfig, localDeps []string) {
 
         case <-sigChan:
             fmt.Println()
-            fmt.Printf("%sStopping watch mode...%s\n", colors.Yellow, colors.Reset)
+            s.stop()
             return
         }
     }
 }
 
-// handleFileChange handles a file change event
-func handleFileChange(event fsnotify.Event, config WatchConfig, localDeps []string) {
+// stop cancels a running build before watch mode ends
+func (s *WatchSession) stop() {
+    fmt.Printf("%sStopping watch mode...%s\n", colors.Yellow, colors.Reset)
 
-    // Check if change is in a local dependency
-    isLocalDep := false
-    var changedDepPath string
+    if s.building {
+        s.cancelBuild()
+        <-s.buildDone
+    }
+}
 
-    for _, dep := range localDeps {
+// printKeyHelp prints the keyboard controls
+func printKeyHelp() {
+    fmt.Printf("%sKeys:%s r rebuild · t run tests · d rebuild local deps · p pause · l last log · c clear · q quit\n", colors.Yellow, colors.Reset)
+    fmt.Println()
+}
 
-        if strings.HasPrefix(event.Name, dep) {
-            isLocalDep = true
-            changedDepPath = dep
-            break
-        }
+// handleKey runs the command for a key press, returns false to quit
+func (s *WatchSession) handleKey(key byte) bool {
+    switch key {
+    case 'q':
+        fmt.Println()
+        return false
+
+    case 'c':
+        // Clear the screen and move the cursor home
+        fmt.Print("\033[H\033[2J")
+        printKeyHelp()
+
+    case 'h', '?':
+        printKeyHelp()
+
+    case 'l':
+        printLastBuildLog()
+
+    case 'p':
+        s.togglePause()
+
+    case 'r':
+        defaultRule := s.rules[len(s.rules)-1]
+
+        s.startManualJob("Rebuild requested", func(ctx context.Context) BuildResult {
+            return s.handleFileChange(ctx, defaultRule, nil)
+        })
+
+    case 't':
+        s.startManualJob("Running all tests", func(ctx context.Context) BuildResult {
+            return BuildResult{Success: runWatchTests(ctx, "all", nil)}
+        })
+
+    case 'd':
+        s.startManualJob("Rebuilding all local dependencies", s.rebuildDependencies)
+    }
+
+    return true
+}
+
+// startManualJob runs a job requested from the keyboard unless a build is running
+func (s *WatchSession) startManualJob(title string, job func(ctx context.Context) BuildResult) {
+
+    if s.building {
+        fmt.Printf("%sA build is running, try again when it has finished%s\n", colors.Yellow, colors.Reset)
+        return
     }
 
     fmt.Println()
     fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colors.Yellow, colors.Reset)
-    fmt.Printf("%sChange detected:%s %s\n", colors.Yellow, colors.Reset, event.Name)
-    fmt.Printf("%sEvent:%s %s\n", colors.Yellow, colors.Reset, event.Op.String())
+    fmt.Printf("%s%s%s\n", colors.Yellow, title, colors.Reset)
 
-    if isLocalDep {
+    s.startJob(func(ctx context.Context) BuildResult {
+        defer func() {
+            fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colors.Yellow, colors.Reset)
+            fmt.Println()
+        }()
+
+        return job(ctx)
+    })
+}
+
+// togglePause pauses or resumes building on changes
+// Changes made while paused are kept and built on resume
+func (s *WatchSession) togglePause() {
+    s.paused = !s.paused
+
+    if s.paused {
+        fmt.Printf("%s⏸ Paused, changes are collected but not built (press p to resume)%s\n", colors.Yellow, colors.Reset)
+        return
+    }
+
+    fmt.Printf("%s▶ Resumed%s\n", colors.Green, colors.Reset)
+
+    if s.reloadPending && !s.building {
+        s.reloadConfig(true)
+    }
+
+    for _, rule := range s.rules {
+
+        if len(rule.pending) > 0 {
+            s.ruleReady(rule)
+        }
+    }
+}
+
+// rebuildDependencies installs every local dependency ignoring the stored hashes and rebuilds the project
+func (s *WatchSession) rebuildDependencies(ctx context.Context) BuildResult {
+    if len(s.localDeps) == 0 {
+        fmt.Printf("%sNo local dependencies%s\n", colors.Green, colors.Reset)
+    }
+
+    for _, dep := range s.localDeps {
+        relPath, err := filepath.Rel(currentDir, dep)
+        if err != nil {
+            relPath = dep
+        }
+
+        fmt.Printf("%sLinking dependency: %s%s\n", colors.Blue, relPath, colors.Reset)
+
+        start := time.Now()
+
+        if err := installDependency(ctx, dep, s.config.SkipTests); err != nil {
+
+            if ctx.Err() != nil {
+                fmt.Printf("%s✗ Build cancelled%s\n", colors.Yellow, colors.Reset)
+            } else {
+                notifyBuild(s.config.Notify, false, err, time.Since(start))
+                fmt.Printf("%sFailed to link dependency: %s%s\n", colors.Red, dep, colors.Reset)
+            }
+
+            return BuildResult{}
+        }
+
+        fmt.Printf("%s✓ Dependency linked: %s%s\n", colors.Green, relPath, colors.Reset)
+    }
+
+    fmt.Printf("%sRebuilding...%s\n", colors.Green, colors.Reset)
+
+    start := time.Now()
+
+    success, err := runMvnBuild(ctx, s.config.BuildCommand, s.config.SkipTests)
+    if ctx.Err() == nil {
+        notifyBuild(s.config.Notify, success, err, time.Since(start))
+    }
+
+    if !success {
+
+        if ctx.Err() != nil {
+            fmt.Printf("%s✗ Build cancelled%s\n", colors.Yellow, colors.Reset)
+        } else {
+            fmt.Printf("%s✗ Build failed!%s\n", colors.Red, colors.Reset)
+        }
+
+        return BuildResult{}
+    }
+
+    fmt.Printf("%s✓ Build successful!%s\n", colors.Green, colors.Reset)
+
+    if s.config.PostCommand != "" {
+        reloadApp(s.app, s.swapper, false)
+    }
+
+    return BuildResult{Success: true}
+}
+
+// handleEvent routes a file event to the first matching rule and restarts its debounce timer
+// The timer restarts on every event, so the rule fires once its paths are quiet
+func (s *WatchSession) handleEvent(event fsnotify.Event) {
+
+    // Skip non-relevant events
+    if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+        return
+    }
+
+    isConfigFile := s.configFiles[event.Name]
+
+    if isConfigFile {
+        s.configChanged(event.Name)
+    }
+
+    if ignored, reason := s.filter.Ignored(event); ignored {
+        s.logIgnored(event, reason)
+        return
+    }
+
+    // Configuration files may also have a rule of their own
+    rule := s.matchRule(event.Name)
+    if rule == nil {
+
+        if !isConfigFile {
+            s.logIgnored(event, "no matching watch rule")
+        }
+
+        return
+    }
+
+    s.addChange(rule, event.Name, event.Op)
+}
+
+// addChange adds a change to a rule and restarts its debounce timer
+func (s *WatchSession) addChange(rule *WatchRule, path string, op fsnotify.Op) {
+    rule.pending[path] |= op
+
+    if rule.timer == nil {
+        rule.timer = time.AfterFunc(rule.DebounceTime, func() {
+            s.ruleFired <- rule
+        })
+    } else {
+        rule.timer.Reset(rule.DebounceTime)
+    }
+}
+
+// configChanged schedules a configuration reload once the configuration files are quiet
+func (s *WatchSession) configChanged(path string) {
+    s.configChanges[path] = true
+
+    if s.configTimer == nil {
+        s.configTimer = time.AfterFunc(s.config.DebounceTime, func() {
+            select {
+            case s.configFired <- struct{}{}:
+            default:
+            }
+        })
+    } else {
+        s.configTimer.Reset(s.config.DebounceTime)
+    }
+}
+
+// logIgnored prints an ignored event in verbose mode
+func (s *WatchSession) logIgnored(event fsnotify.Event, reason string) {
+
+    if s.config.Verbose {
+        fmt.Printf("%sIgnored %s %s: %s%s\n", colors.Blue, event.Op.String(), event.Name, reason, colors.Reset)
+    }
+}
+
+// matchRule finds the rule responsible for a path
+// Changes in local dependencies always go to the default rule, which links them
+func (s *WatchSession) matchRule(path string) *WatchRule {
+    defaultRule := s.rules[len(s.rules)-1]
+
+    for _, dep := range s.localDeps {
+
+        if strings.HasPrefix(path, dep) {
+            return defaultRule
+        }
+    }
+
+    relPath, err := filepath.Rel(currentDir, path)
+    if err != nil {
+        return nil
+    }
+
+    info, err := os.Stat(path)
+    isDir := err == nil && info.IsDir()
+
+    for _, rule := range s.rules {
+
+        if rule.Matches(relPath, isDir) {
+            return rule
+        }
+    }
+
+    return nil
+}
+
+// ruleReady starts the build for a rule whose debounce window has passed
+// While a build runs, the rule is queued once and optionally cancels the running build
+func (s *WatchSession) ruleReady(rule *WatchRule) {
+
+    if len(rule.pending) == 0 || s.paused {
+        return
+    }
+
+    if !s.building {
+        s.startBuild(rule)
+        return
+    }
+
+    if !rule.queued {
+        rule.queued = true
+        s.queue = append(s.queue, rule)
+        fmt.Printf("%sChanges detected during build, rebuilding afterwards%s\n", colors.Yellow, colors.Reset)
+    }
+
+    if s.config.CancelOnChange {
+        fmt.Printf("%sCancelling running build...%s\n", colors.Yellow, colors.Reset)
+        s.cancelBuild()
+    }
+}
+
+// startNextBuild starts the next queued rule with pending changes
+func (s *WatchSession) startNextBuild() {
+
+    for len(s.queue) > 0 {
+        rule := s.queue[0]
+        s.queue = s.queue[1:]
+        rule.queued = false
+
+        if len(rule.pending) > 0 {
+            s.startBuild(rule)
+            return
+        }
+    }
+}
+
+// startBuild runs a rule for its pending changes in the background
+func (s *WatchSession) startBuild(rule *WatchRule) {
+    changes := rule.pending
+    rule.pending = make(map[string]fsnotify.Op)
+
+    s.startJob(func(ctx context.Context) BuildResult {
+        result := s.handleFileChange(ctx, rule, changes)
+
+        if !result.Success && ctx.Err() != nil {
+            result.Unbuilt = changes
+        }
+
+        return result
+    })
+}
+
+// requeueChanges puts the changes of a cancelled build back and queues their rules
+func (s *WatchSession) requeueChanges(changes map[string]fsnotify.Op) {
+
+    for path, op := range changes {
+        rule := s.matchRule(path)
+        if rule == nil {
+            continue
+        }
+
+        rule.pending[path] |= op
+
+        if !rule.queued {
+            rule.queued = true
+            s.queue = append(s.queue, rule)
+        }
+    }
+}
+
+// startJob runs a build job in the background, only one job runs at a time
+func (s *WatchSession) startJob(job func(ctx context.Context) BuildResult) {
+    ctx, cancel := context.WithCancel(context.Background())
+    s.cancelBuild = cancel
+    s.building = true
+
+    go func() {
+        s.buildDone <- job(ctx)
+    }()
+}
+
+// reloadConfig re-reads pom.xml, its parents and .env and applies the differences
+// Options given on the command line are kept, dependency changes trigger a rebuild if rebuild is set
+func (s *WatchSession) reloadConfig(rebuild bool) {
+    changed := s.configChanges
+    s.configChanges = make(map[string]bool)
+    s.reloadPending = false
+
+    if _, err := os.Stat(pomFile); err != nil {
+        fmt.Printf("%sWarning: pom.xml not found, keeping the current watch configuration%s\n", colors.Yellow, colors.Reset)
+        return
+    }
+
+    fmt.Println()
+
+    if len(changed) > 0 {
+        var names []string
+
+        for path := range changed {
+            names = append(names, s.filter.relPath(path))
+        }
+
+        sort.Strings(names)
+        fmt.Printf("%sConfiguration changed:%s %s\n", colors.Yellow, colors.Reset, strings.Join(names, " "))
+    }
+
+    if changed[filepath.Join(currentDir, ".env")] {
+        keys, err := reloadEnvFile()
+        if err != nil {
+            fmt.Printf("%sWarning: Could not read .env: %v%s\n", colors.Yellow, err, colors.Reset)
+        } else if len(keys) > 0 {
+            fmt.Printf("  %s~ .env:%s %s\n", colors.Yellow, colors.Reset, strings.Join(keys, ", "))
+        }
+    }
+
+    config := loadWatchConfig()
+    config.Debug = s.config.Debug
+    config.Verbose = s.config.Verbose
+    config.PostCommand = s.app.Command
+
+    if s.config.Test != "" && config.Test == "" {
+        config.Test = s.config.Test
+    }
+
+    addTestDirs(&config)
+
+    // Pending changes are routed through the new rules
+    pending := make(map[string]fsnotify.Op)
+
+    for _, rule := range s.rules {
+
+        if rule.timer != nil {
+            rule.timer.Stop()
+        }
+
+        for path, op := range rule.pending {
+            pending[path] |= op
+        }
+    }
+
+    oldRoots := s.roots
+    oldDeps := s.localDeps
+
+    s.config = config
+    s.localDeps = getLocalDependencies()
+    s.queue = nil
+    s.applyConfig(loadWatchRules(config))
+
+    printListDiff("watching", rootList(oldRoots), rootList(s.roots))
+    printListDiff("local dependency", relPaths(oldDeps), relPaths(s.localDeps))
+
+    for path, op := range pending {
+
+        if rule := s.matchRule(path); rule != nil {
+            s.addChange(rule, path, op)
+        }
+    }
+
+    defaultRule := s.rules[len(s.rules)-1]
+
+    // New local dependencies are linked by the default rule, unchanged ones are skipped by their hash
+    for _, dep := range s.localDeps {
+
+        if !containsString(oldDeps, dep) {
+            s.addChange(defaultRule, dep, fsnotify.Create)
+        }
+    }
+
+    fingerprint := getDependencyFingerprint()
+
+    if fingerprint != s.depsFingerprint {
+        s.depsFingerprint = fingerprint
+
+        if rebuild {
+            fmt.Printf("%sDependencies changed, rebuilding%s\n", colors.Yellow, colors.Reset)
+
+            rule := s.matchRule(pomFile)
+            if rule == nil {
+                rule = defaultRule
+            }
+
+            s.addChange(rule, pomFile, fsnotify.Write)
+        }
+    }
+
+    fmt.Printf("%s✓ Watch configuration reloaded%s\n", colors.Green, colors.Reset)
+}
+
+// printListDiff prints the entries added to and removed from a list
+func printListDiff(label string, old, current []string) {
+    for _, entry := range current {
+
+        if !containsString(old, entry) {
+            fmt.Printf("  %s+ %s:%s %s\n", colors.Green, label, colors.Reset, entry)
+        }
+    }
+
+    for _, entry := range old {
+
+        if !containsString(current, entry) {
+            fmt.Printf("  %s- %s:%s %s\n", colors.Red, label, colors.Reset, entry)
+        }
+    }
+}
+
+// rootList returns the watched directories relative to the project, sorted
+func rootList(roots map[string]bool) []string {
+    list := make([]string, 0, len(roots))
+
+    for dir := range roots {
+        list = append(list, dir)
+    }
+
+    return relPaths(list)
+}
+
+// relPaths returns paths relative to the project, sorted
+func relPaths(paths []string) []string {
+    rel := make([]string, 0, len(paths))
+
+    for _, path := range paths {
+
+        if r, err := filepath.Rel(currentDir, path); err == nil {
+            path = r
+        }
+
+        rel = append(rel, path)
+    }
+
+    sort.Strings(rel)
+    return rel
+}
+
+// containsString checks if a list contains a string
+func containsString(list []string, value string) bool {
+    for _, entry := range list {
+
+        if entry == value {
+            return true
+        }
+    }
+
+    return false
+}
+
+// newWatchFilter creates the filter for the configured include and ignore patterns
+func newWatchFilter(config WatchConfig, localDeps []string) *WatchFilter {
+    return &WatchFilter{
+        include:   newPathMatcher(config.Include),
+        ignore:    newPathMatcher(config.Ignore),
+        localDeps: localDeps,
+    }
+}
+
+// Ignored checks if an event should not trigger a rebuild and explains why
+func (f *WatchFilter) Ignored(event fsnotify.Event) (bool, string) {
+    info, err := os.Stat(event.Name)
+    isDir := err == nil && info.IsDir()
+
+    // Files created inside a new directory are reported on their own
+    if isDir && event.Op&fsnotify.Create != 0 {
+        return true, "directory created"
+    }
+
+    relPath := f.relPath(event.Name)
+
+    if matched, pattern := f.ignore.Match(relPath, isDir); matched {
+        return true, fmt.Sprintf("matches ignore pattern '%s'", pattern)
+    }
+
+    if !f.include.Empty() {
+
+        if matched, _ := f.include.Match(relPath, isDir); !matched {
+            return true, "not matched by watch.include"
+        }
+    }
+
+    return false, ""
+}
+
+// relPath returns a path relative to the project or the local dependency containing it
+func (f *WatchFilter) relPath(path string) string {
+    for _, dep := range f.localDeps {
+
+        if relPath, err := filepath.Rel(dep, path); err == nil && !strings.HasPrefix(relPath, "..") {
+            return relPath
+        }
+    }
+
+    if relPath, err := filepath.Rel(currentDir, path); err == nil {
+        return relPath
+    }
+
+    return path
+}
+
+// handleFileChange runs a rule for a batch of file changes
+func (s *WatchSession) handleFileChange(ctx context.Context, rule *WatchRule, changes map[string]fsnotify.Op) BuildResult {
+    config := s.config
+
+    // Find the local dependencies that changed
+    var changedDeps []string
+
+    for _, dep := range s.localDeps {
+
+        for path := range changes {
+
+            if strings.HasPrefix(path, dep) {
+                changedDeps = append(changedDeps, dep)
+                break
+            }
+        }
+    }
+
+    isLocalDep := len(changedDeps) > 0
+
+    // Manual rebuilds print their own header
+    if len(changes) > 0 {
+        fmt.Println()
+        fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colors.Yellow, colors.Reset)
+        printChanges(changes)
+
+        if rule.Name != "default" {
+            fmt.Printf("%sRule:%s %s (%s)\n", colors.Yellow, colors.Reset, rule.Name, rule.Describe())
+        }
+
+        defer func() {
+            fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colors.Yellow, colors.Reset)
+            fmt.Println()
+        }()
+    }
+
+    for _, changedDepPath := range changedDeps {
         // Check if dependency actually needs to be rebuilt
         shouldRebuild, currentHash, storedHash, err := shouldRebuildDependency(changedDepPath)
         if err != nil {
@@ -254,116 +1177,252 @@ func handleFileChange(event fsnotify.Event, config WatchConfig, localDeps []stri
         if !shouldRebuild {
             fmt.Printf("%sSkipping dependency (no changes): %s%s\n", colors.Green, relPath, colors.Reset)
             printHashComparison(changedDepPath, currentHash, storedHash)
-        } else {
-            fmt.Printf("%sLinking dependency and rebuilding...%s\n", colors.Green, colors.Reset)
+            continue
+        }
 
-            // Build and install the dependency
-            fmt.Printf("%sLinking dependency: %s%s\n", colors.Blue, relPath, colors.Reset)
-            printHashComparison(changedDepPath, currentHash, storedHash)
+        fmt.Printf("%sLinking dependency and rebuilding...%s\n", colors.Green, colors.Reset)
 
-            args := []string{"clean", "install"}
-            if config.SkipTests {
-                args = append(args, "-DskipTests")
-            }
+        // Build and install the dependency
+        fmt.Printf("%sLinking dependency: %s%s\n", colors.Blue, relPath, colors.Reset)
+        printHashComparison(changedDepPath, currentHash, storedHash)
 
-            cmd := exec.Command("mvn", args...)
-            cmd.Dir = changedDepPath
-            cmd.Stdout = io.Discard
-            cmd.Stderr = io.Discard
+        start := time.Now()
 
-            if err := cmd.Run(); err != nil {
-                fmt.Printf("%sFailed to link dependency: %s%s\n", colors.Red, changedDepPath, colors.Reset)
-                return
-            }
+        if err := installDependency(ctx, changedDepPath, config.SkipTests); err != nil {
 
-            // Update hash after successful build
-            if err := updateSrcHash(changedDepPath); err != nil {
-                // Log but don't fail the build if hash update fails
-                fmt.Printf("%sWarning: Could not update hash for %s: %v%s\n", colors.Yellow, changedDepPath, err, colors.Reset)
+            if ctx.Err() != nil {
+                fmt.Printf("%s✗ Build cancelled%s\n", colors.Yellow, colors.Reset)
+            } else {
+                notifyBuild(config.Notify, false, err, time.Since(start))
+                fmt.Printf("%sFailed to link dependency: %s%s\n", colors.Red, changedDepPath, colors.Reset)
             }
 
-            fmt.Printf("%s✓ Dependency linked: %s%s\n", colors.Green, relPath, colors.Reset)
+            return BuildResult{}
         }
+
+        fmt.Printf("%s✓ Dependency linked: %s%s\n", colors.Green, relPath, colors.Reset)
     }
 
-    // Rebuild project
-    fmt.Printf("%sRebuilding...%s\n", colors.Green, colors.Reset)
+    var success bool
+    var err error
 
-    success, _ := runMvnBuild(config.BuildCommand, config.SkipTests)
-    if success {
+    start := time.Now()
+
+    switch rule.Action {
+    case ruleActionScript:
+        success = runWatchScript(rule.Script)
+
+    case ruleActionFull:
+        fmt.Printf("%sRunning full rebuild...%s\n", colors.Green, colors.Reset)
+        success, err = runMvnBuild(ctx, "clean install -U", config.SkipTests)
+
+    default:
+        fmt.Printf("%sRebuilding...%s\n", colors.Green, colors.Reset)
+        success, err = runMvnBuild(ctx, rule.Command, config.SkipTests)
+    }
+
+    // Maven builds report their result, cancelled ones are replaced by the next build
+    if rule.Action != ruleActionScript && ctx.Err() == nil {
+        notifyBuild(config.Notify, success, err, time.Since(start))
+    }
+
+    if !success {
 
-        if isLocalDep {
-            fmt.Printf("%s✓ Dependency linked and build successful!%s\n", colors.Green, colors.Reset)
+        if ctx.Err() != nil {
+            fmt.Printf("%s✗ Build cancelled%s\n", colors.Yellow, colors.Reset)
         } else {
-            fmt.Printf("%s✓ Build successful!%s\n", colors.Green, colors.Reset)
+            fmt.Printf("%s✗ Build failed!%s\n", colors.Red, colors.Reset)
         }
 
-        if config.PostCommand != "" {
-            fmt.Printf("%sRunning post command: %s%s\n", colors.Yellow, config.PostCommand, colors.Reset)
-            runShellCommand(config.PostCommand)
-        }
+        return BuildResult{}
+    }
+
+    if isLocalDep {
+        fmt.Printf("%s✓ Dependency linked and build successful!%s\n", colors.Green, colors.Reset)
     } else {
-        fmt.Printf("%s✗ Build failed!%s\n", colors.Red, colors.Reset)
+        fmt.Printf("%s✓ Build successful!%s\n", colors.Green, colors.Reset)
     }
 
-    fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colors.Yellow, colors.Reset)
-    fmt.Println()
-}
+    if rule.PostCommand != "" {
+        fmt.Printf("%sRunning post command: %s%s\n", colors.Yellow, rule.PostCommand, colors.Reset)
+        runShellCommand(rule.PostCommand)
+    }
 
-// runMvnBuild runs Maven build and captures output
-func runMvnBuild(command string, skipTests bool) (bool, error) {
-    mvnCmd := getMvnCommand()
+    if rule.Reload && config.PostCommand != "" {
+        // Only compiled classes can be hot-swapped
+        reloadApp(s.app, s.swapper, rule.Action == ruleActionBuild && !isLocalDep)
+    }
 
-    args := strings.Fields(command)
+    // Tests run after the application is up to date
+    if config.Test != "" && rule.Action == ruleActionBuild {
+        runWatchTests(ctx, config.Test, changes)
+    }
+
+    return BuildResult{
+        Success:      true,
+        ReloadConfig: rule.Action == ruleActionFull,
+    }
+}
+
+// installDependency runs 'mvn clean install' in a local dependency and updates its source hash
+func installDependency(ctx context.Context, depPath string, skipTests bool) error {
+    args := []string{"clean", "install"}
     if skipTests {
         args = append(args, "-DskipTests")
     }
 
-    cmd := exec.Command(mvnCmd, args...)
-    cmd.Dir = currentDir
+    maven := findMaven(depPath)
+    cmd := maven.Command(ctx, depPath, args...)
+
+    // The full output goes to .marn/logs
+    log, err := newBuildLog(depPath, maven, args)
+    if err != nil {
+        fmt.Printf("%sWarning: Could not create build log: %v%s\n", colors.Yellow, err, colors.Reset)
+    } else {
+        defer log.Close()
+        cmd.Stdout = log
+        cmd.Stderr = log
+    }
 
-    // Capture output
-    stdout, _ := cmd.StdoutPipe()
-    stderr, _ := cmd.StderrPipe()
+    if err := cmd.Run(); err != nil {
+        collector := newDiagnosticCollector()
 
-    if err := cmd.Start(); err != nil {
-        return false, err
+        if log != nil && ctx.Err() == nil && collector.AddFile(log.Path) == nil {
+            printDiagnostics(collector.Diagnostics())
+            fmt.Printf("%sFull log: %s%s\n", colors.Yellow, log.RelPath(), colors.Reset)
+        }
+
+        return &BuildError{Err: err, Diagnostics: collector.Diagnostics()}
     }
 
-    // Read and filter output
-    go filterOutput(stdout)
-    go filterOutput(stderr)
+    // Update hash after successful build
+    if err := updateSrcHash(depPath); err != nil {
+        // Log but don't fail the build if hash update fails
+        fmt.Printf("%sWarning: Could not update hash for %s: %v%s\n", colors.Yellow, depPath, err, colors.Reset)
+    }
 
-    err := cmd.Wait()
-    return err == nil, err
+    return nil
+}
+
+// runWatchScript runs a script from pom.xml for a watch rule
+func runWatchScript(name string) bool {
+    scripts := getScriptsFromPom()
+
+    script, exists := scripts[name]
+    if !exists {
+        fmt.Printf("%sError: Script '%s' not found in pom.xml%s\n", colors.Red, name, colors.Reset)
+        return false
+    }
+
+    fmt.Printf("%sRunning script: %s%s\n", colors.Green, name, colors.Reset)
+    return runShellCommand(script) == nil
 }
 
-// filterOutput filters Maven output to show only relevant lines
-func filterOutput(r io.Reader) {
-    scanner := bufio.NewScanner(r)
-    re := regexp.MustCompile(`(ERROR|BUILD|Compiling|SUCCESS|FAILURE)`)
+// printChanges prints the paths of a batch of changes
+func printChanges(changes map[string]fsnotify.Op) {
+    paths := make([]string, 0, len(changes))
+
+    for path := range changes {
+        paths = append(paths, path)
+    }
+
+    sort.Strings(paths)
+
+    if len(paths) == 1 {
+        fmt.Printf("%sChange detected:%s %s\n", colors.Yellow, colors.Reset, paths[0])
+        fmt.Printf("%sEvent:%s %s\n", colors.Yellow, colors.Reset, changes[paths[0]].String())
+        return
+    }
+
+    fmt.Printf("%sChanges detected:%s %d files\n", colors.Yellow, colors.Reset, len(paths))
 
-    for scanner.Scan() {
-        line := scanner.Text()
+    const maxShown = 10
 
-        if re.MatchString(line) {
-            fmt.Println(line)
+    for i, path := range paths {
+
+        if i == maxShown {
+            fmt.Printf("  ... and %d more\n", len(paths)-maxShown)
+            break
         }
+
+        fmt.Printf("  %s (%s)\n", path, changes[path].String())
     }
 }
 
-// addDirRecursive adds a directory and all subdirectories to the watcher
-func addDirRecursive(watcher *fsnotify.Watcher, dir string) error {
-    return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// reloadApp brings the application up to date after a successful build
+// Changed classes are hot-swapped when possible, otherwise the application is restarted
+func reloadApp(app *ManagedApp, swapper *Hotswapper, tryHotswap bool) {
 
-        if err != nil {
-            return err
+    if swapper != nil && tryHotswap && app.Running() {
+        count, err := swapper.Swap()
+        if err == nil && count == 0 {
+            fmt.Printf("%s✓ No class changes to hot-swap%s\n", colors.Green, colors.Reset)
+            return
         }
 
-        if info.IsDir() {
-            return watcher.Add(path)
+        if err == nil {
+            fmt.Printf("%s✓ Hot-swapped %d class(es)%s\n", colors.Green, count, colors.Reset)
+            return
         }
 
-        return nil
+        fmt.Printf("%sHot-swap not possible (%v), restarting...%s\n", colors.Yellow, err, colors.Reset)
+    }
+
+    app.Restart()
+
+    // The new JVM starts with the classes currently on disk
+    if swapper != nil {
+        swapper.Snapshot()
+    }
+}
+
+// runMvnBuild runs Maven build and captures output
+// The full output goes to .marn/logs, on failure the diagnostics are printed
+// Cancelling the context kills the build
+func runMvnBuild(ctx context.Context, command string, skipTests bool) (bool, error) {
+    args := strings.Fields(command)
+    if skipTests {
+        args = append(args, "-DskipTests")
+    }
+
+    maven := findMaven(currentDir)
+    cmd := maven.Command(ctx, currentDir, args...)
+
+    log, err := newBuildLog(currentDir, maven, args)
+    if err != nil {
+        fmt.Printf("%sWarning: Could not create build log: %v%s\n", colors.Yellow, err, colors.Reset)
+    }
+
+    collector := newDiagnosticCollector()
+
+    lines := newLineWriter(func(line string) {
+        if log != nil {
+            log.WriteLine(line)
+        }
+
+        collector.AddLine(line)
     })
+
+    cmd.Stdout = lines
+    cmd.Stderr = lines
+
+    err = cmd.Run()
+    lines.Flush()
+
+    if log != nil {
+        log.Close()
+    }
+
+    if err != nil && ctx.Err() == nil {
+        diagnostics := collector.Diagnostics()
+        printDiagnostics(diagnostics)
+
+        if log != nil {
+            fmt.Printf("%sFull log: %s%s\n", colors.Yellow, log.RelPath(), colors.Reset)
+        }
+
+        return false, &BuildError{Err: err, Diagnostics: diagnostics}
+    }
+
+    return err == nil, err
 }
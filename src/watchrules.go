@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch rule actions
+const (
+	ruleActionBuild  = "build"  // run Maven goals
+	ruleActionScript = "script" // run a script.* from pom.xml
+	ruleActionFull   = "full"   // clean install, refresh dependencies and reload the configuration
+)
+
+// WatchRule maps a set of path patterns to what happens when they change
+type WatchRule struct {
+	Name         string
+	Paths        []string
+	Action       string
+	Command      string
+	Script       string
+	DebounceTime time.Duration
+	PostCommand  string
+	Reload       bool
+
+	matcher *PathMatcher
+	pending map[string]fsnotify.Op
+	timer   *time.Timer
+	queued  bool
+}
+
+// loadWatchRules loads the watch.rule.<name>.* rules from pom.xml
+// A default rule for watch.dirs and watch.buildCommand always comes last
+func loadWatchRules(config WatchConfig) []*WatchRule {
+	var rules []*WatchRule
+
+	for _, name := range getWatchRuleNames() {
+		prefix := "watch.rule." + name + "."
+
+		rule := &WatchRule{
+			Name:         name,
+			Paths:        strings.Fields(getProperty(prefix + "paths")),
+			Action:       getProperty(prefix + "action"),
+			Command:      getProperty(prefix + "command"),
+			Script:       getProperty(prefix + "script"),
+			DebounceTime: config.DebounceTime,
+			PostCommand:  getProperty(prefix + "postCommand"),
+			Reload:       true,
+		}
+
+		if len(rule.Paths) == 0 {
+			fmt.Printf("%sWarning: Watch rule '%s' has no paths, ignoring it%s\n", colors.Yellow, name, colors.Reset)
+			continue
+		}
+
+		if rule.Action == "" {
+
+			if rule.Script != "" {
+				rule.Action = ruleActionScript
+			} else {
+				rule.Action = ruleActionBuild
+			}
+		}
+
+		if rule.Action == ruleActionBuild && rule.Command == "" {
+			rule.Command = config.BuildCommand
+		}
+
+		if rule.Action == ruleActionScript {
+			// Scripts usually don't change the classes of the running application
+			rule.Reload = false
+		}
+
+		rule.DebounceTime = getDurationProperty(prefix+"debounceTime", rule.DebounceTime)
+
+		if reload := getProperty(prefix + "reload"); reload != "" {
+			rule.Reload = reload == "true"
+		}
+
+		if rule.Action != ruleActionBuild && rule.Action != ruleActionScript && rule.Action != ruleActionFull {
+			fmt.Printf("%sWarning: Watch rule '%s' has unknown action '%s', ignoring it%s\n", colors.Yellow, name, rule.Action, colors.Reset)
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	// The default rule covers watch.dirs with watch.buildCommand
+	defaultRule := &WatchRule{
+		Name:         "default",
+		Action:       ruleActionBuild,
+		Command:      config.BuildCommand,
+		DebounceTime: config.DebounceTime,
+		PostCommand:  "",
+		Reload:       true,
+	}
+
+	for _, dir := range strings.Fields(config.WatchDirs) {
+		defaultRule.Paths = append(defaultRule.Paths, strings.TrimSuffix(filepath.ToSlash(dir), "/")+"/**")
+	}
+
+	rules = append(rules, defaultRule)
+
+	for _, rule := range rules {
+		rule.matcher = newPathMatcher(anchorPatterns(rule.Paths))
+		rule.pending = make(map[string]fsnotify.Op)
+	}
+
+	return rules
+}
+
+// getWatchRuleNames returns the rule names in the order they appear in pom.xml
+func getWatchRuleNames() []string {
+	content, err := os.ReadFile(pomFile)
+	if err != nil {
+		return nil
+	}
+
+	var pom POM
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+
+	// Only real properties count, not comments or other elements
+	for _, property := range pom.Properties.Names() {
+		rest, ok := strings.CutPrefix(property, "watch.rule.")
+		if !ok {
+			continue
+		}
+
+		name, _, ok := strings.Cut(rest, ".")
+		if !ok || name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// anchorPatterns makes rule paths relative to the project root
+// Unlike ignore patterns, "pom.xml" only means the project's own pom.xml
+func anchorPatterns(paths []string) []string {
+	anchored := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		path = filepath.ToSlash(path)
+
+		if strings.HasPrefix(path, "!") {
+			anchored = append(anchored, "!/"+strings.TrimPrefix(path[1:], "/"))
+		} else {
+			anchored = append(anchored, "/"+strings.TrimPrefix(path, "/"))
+		}
+	}
+
+	return anchored
+}
+
+// Matches checks if a path relative to the project root belongs to the rule
+func (r *WatchRule) Matches(relPath string, isDir bool) bool {
+	matched, _ := r.matcher.Match(relPath, isDir)
+	return matched
+}
+
+// Describe returns a short description of what the rule does
+func (r *WatchRule) Describe() string {
+	switch r.Action {
+	case ruleActionScript:
+		return "script " + r.Script
+	case ruleActionFull:
+		return "full rebuild"
+	default:
+		return r.Command
+	}
+}
+
+// ruleRoots returns the directories that must be watched for the rules
+// Maps each directory to whether it is watched recursively
+func ruleRoots(rules []*WatchRule) map[string]bool {
+	roots := make(map[string]bool)
+
+	for _, rule := range rules {
+
+		for _, path := range rule.Paths {
+
+			if strings.HasPrefix(path, "!") {
+				continue
+			}
+
+			dir, recursive := patternRoot(path)
+
+			if !roots[dir] {
+				roots[dir] = recursive
+			}
+		}
+	}
+
+	return roots
+}
+
+// patternRoot returns the directory to watch for a path pattern
+// The static part before the first wildcard is watched recursively, a plain file through its directory
+func patternRoot(pattern string) (string, bool) {
+	var static []string
+	hasGlob := false
+
+	for _, part := range strings.Split(strings.Trim(filepath.ToSlash(pattern), "/"), "/") {
+
+		if strings.ContainsAny(part, "*?[") {
+			hasGlob = true
+			break
+		}
+
+		static = append(static, part)
+	}
+
+	path := filepath.Join(append([]string{currentDir}, static...)...)
+
+	if hasGlob {
+		return path, true
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path, true
+	}
+
+	return filepath.Dir(path), false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// WhyOptions holds the options passed to 'marn why'
+type WhyOptions struct {
+	Spec    DependencySpec
+	Refresh bool
+}
+
+// parseWhyArgs parses the arguments given to 'marn why'
+func parseWhyArgs(args []string) (WhyOptions, error) {
+	var opts WhyOptions
+	found := false
+
+	for _, arg := range args {
+
+		switch {
+		case arg == "--refresh":
+			opts.Refresh = true
+
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown option '%s'", arg)
+
+		case found:
+			return opts, fmt.Errorf("only one artifact can be explained at a time")
+
+		default:
+			spec, err := parseDependencySpec(arg)
+			if err != nil {
+				return opts, err
+			}
+
+			opts.Spec = spec
+			found = true
+		}
+	}
+
+	if !found {
+		return opts, fmt.Errorf("no artifact given, use marn why <group:artifact>")
+	}
+
+	return opts, nil
+}
+
+// showWhy implements 'marn why', printing every path that brings an artifact into the project
+func showWhy() {
+	opts, err := parseWhyArgs(os.Args[2:])
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	root, err := loadDependencyTree(opts.Refresh)
+	if err != nil {
+		fmt.Printf("%sError: Could not resolve the dependency tree: %v%s\n", colors.Red, err, colors.Reset)
+		os.Exit(1)
+	}
+
+	var matches []*DependencyNode
+
+	root.Walk(func(node *DependencyNode) {
+		spec := opts.Spec
+
+		if node != root && node.ArtifactID == spec.ArtifactID && (spec.GroupID == "" || node.GroupID == spec.GroupID) && (spec.Version == "" || node.Version == spec.Version) {
+			matches = append(matches, node)
+		}
+	})
+
+	if len(matches) == 0 {
+		fmt.Printf("%s%s is not a dependency of %s%s\n", colors.Yellow, opts.Spec, root.Key(), colors.Reset)
+		os.Exit(1)
+	}
+
+	for _, match := range matches {
+		fmt.Println()
+		printDependencyPath(match.Path())
+	}
+
+	fmt.Println()
+	printWhySummary(matches)
+}
+
+// printDependencyPath prints the chain of artifacts from the project to a dependency
+func printDependencyPath(path []*DependencyNode) {
+	fmt.Println(path[0].String())
+
+	for i, node := range path[1:] {
+		line := strings.Repeat("   ", i) + "└─ " + node.String()
+
+		if node.Scope != "" {
+			line += " (" + node.Scope + ")"
+		}
+
+		if node.Optional {
+			line += " optional"
+		}
+
+		// The artifact asked about stands out
+		if i == len(path)-2 {
+			color := colors.Green
+			if node.Omitted {
+				color = colors.Yellow
+			}
+
+			line = color + line + colors.Reset
+		}
+
+		if node.Note != "" {
+			line += " " + colors.Yellow + node.Note + colors.Reset
+		}
+
+		fmt.Println(line)
+	}
+}
+
+// printWhySummary prints which version of each matched artifact ends up in the project
+func printWhySummary(matches []*DependencyNode) {
+	var keys []string
+	paths := make(map[string]int)
+	included := make(map[string]*DependencyNode)
+
+	for _, node := range matches {
+
+		if _, ok := paths[node.Key()]; !ok {
+			keys = append(keys, node.Key())
+		}
+
+		paths[node.Key()]++
+
+		if !node.Omitted {
+			included[node.Key()] = node
+		}
+	}
+
+	for _, key := range keys {
+		count := fmt.Sprintf("%d paths", paths[key])
+		if paths[key] == 1 {
+			count = "1 path"
+		}
+
+		node, ok := included[key]
+		if !ok {
+			fmt.Printf("%s%s is not resolved, Maven omitted it on all %s%s\n", colors.Yellow, key, count, colors.Reset)
+			continue
+		}
+
+		direct := ""
+		if node.Parent.Parent == nil {
+			direct = ", it is a direct dependency"
+		}
+
+		fmt.Printf("%s✓ %s resolves to %s (%s) through %s%s%s\n", colors.Green, key, node.Version, node.Scope, count, direct, colors.Reset)
+	}
+}